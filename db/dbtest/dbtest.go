@@ -0,0 +1,34 @@
+// Package dbtest provides empty gala databases for tests.
+package dbtest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+// OpenTx returns a transaction on an empty in-memory database with the gala
+// schema.  It is rolled back, and the database closed, when the test ends.
+func OpenTx(t testing.TB) *sqlx.Tx {
+	// The schema is found relative to this source file, so that it doesn't
+	// matter what directory the test is run in.
+	_, file, _, _ := runtime.Caller(0)
+	schema, err := os.ReadFile(filepath.Join(filepath.Dir(file), "..", "schema.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbh, err := sqlx.Connect("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbh.SetMaxOpenConns(1) // each connection would get its own database
+	t.Cleanup(func() { dbh.Close() })
+	dbh.MustExec(string(schema))
+	tx := dbh.MustBegin()
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
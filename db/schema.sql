@@ -14,7 +14,12 @@ CREATE TABLE gtable (
     num integer NOT NULL DEFAULT 0,
 
     -- Table name.
-    name text NOT NULL DEFAULT ''
+    name text NOT NULL DEFAULT '',
+
+    -- Number of guests the table seats.  Zero means the default capacity
+    -- (defaultTableCapacity in config.json).
+    capacity integer NOT NULL DEFAULT 0
+        CHECK (capacity >= 0)
 );
 
 -- The party table has a row for each party of guests that should be seated
@@ -31,6 +36,25 @@ CREATE TABLE party (
 );
 CREATE UNIQUE INDEX party_place_idx ON party (gtable, place);
 
+-- The partyConstraint table has a row for each seating constraint between two
+-- parties, used when seating parties automatically.  Constraints are
+-- symmetric; each pair of parties appears at most once, with the lower party
+-- ID first.
+CREATE TABLE partyConstraint (
+    -- The two parties.
+    party integer NOT NULL REFERENCES party ON DELETE CASCADE,
+    other integer NOT NULL REFERENCES party ON DELETE CASCADE,
+
+    -- Kind of constraint: "seatWith" means the parties should be seated at
+    -- the same table; "notNear" means they must not be.
+    kind text NOT NULL
+        CHECK (kind IN ('seatWith', 'notNear')),
+
+    PRIMARY KEY (party, other),
+    CHECK (party < other)
+);
+CREATE INDEX partyConstraint_other_idx ON partyConstraint (other);
+
 -- The guest table has a row for each guest at the gala.
 CREATE TABLE guest (
     -- Unique identifier of a guest (not visible to users).
@@ -65,10 +89,11 @@ CREATE TABLE guest (
 
     -- Bidder number for the guest, or 0 if not yet assigned.  Should be unique
     -- except in cases where one guest delegates payment to another; in that
-    -- case the two *may* have the same bidder number.  Note that this is
-    -- presented to the user in hexadecimal, because sometimes there are tables
-    -- with more than 10 bidders.  Bidder numbers for table 12 (decimal) will
-    -- range from 0x120 to 0x12F.
+    -- case the two *may* have the same bidder number.  How bidder numbers are
+    -- assigned and presented depends on the bidderNumbering setting in
+    -- config.json.  By default they are presented to the user in hexadecimal,
+    -- because sometimes there are tables with more than 10 bidders; bidder
+    -- numbers for table 12 (decimal) will range from 0x120 to 0x12F.
     bidder integer NOT NULL DEFAULT 0,
 
     -- Stripe customer ID, if this guest is a customer in Stripe (otherwise
@@ -101,17 +126,110 @@ CREATE TABLE guest (
         CHECK (payer IS NULL OR NOT useCard)
         CHECK (payer!=id),
 
-    -- Entree is the guest's choice of entree.
+    -- Entree is the guest's choice of entree: the code of one of the entrees
+    -- in the entree table, or empty if not yet chosen.
     entree text NOT NULL DEFAULT '',
 
+    -- The guest's dietary restrictions: food allergies (free text), and
+    -- whether they need a gluten-free or vegetarian meal.
+    allergies  text    NOT NULL DEFAULT '',
+    glutenFree boolean NOT NULL DEFAULT 0,
+    vegetarian boolean NOT NULL DEFAULT 0,
+
+    -- The guest's accessibility needs: whether they need wheelchair access or
+    -- hearing assistance.
+    wheelchair    boolean NOT NULL DEFAULT 0,
+    hearingAssist boolean NOT NULL DEFAULT 0,
+
     -- Internal notes about the guest, particularly notes about how they want
     -- to pay for things.
-    notes text NOT NULL DEFAULT ''
+    notes text NOT NULL DEFAULT '',
+
+    -- Whether the guest has cancelled and will not be attending.  Cancelled
+    -- guests are kept for their purchases, but are left out of the counts
+    -- given to the caterer.
+    cancelled boolean NOT NULL DEFAULT 0,
+
+    -- Seat number of the guest at their table, starting from 1.  Zero means
+    -- no seat assigned, which is always the case for cancelled guests and
+    -- guests at tables without numbers.  Seats are assigned automatically so
+    -- that each party sits together, but can be swapped afterward.
+    seat integer NOT NULL DEFAULT 0,
+
+    -- Ribbon printed on the guest's name badge, showing a role or sponsorship
+    -- (e.g. "Board Member" or "Gold Sponsor").  Empty for no ribbon.
+    ribbon text NOT NULL DEFAULT ''
 );
 CREATE INDEX guest_bidder_idx ON guest (bidder);
 CREATE INDEX guest_party_idx  ON guest (party);
 CREATE INDEX guest_payer_idx  ON guest (payer);
 
+-- The bidderFreeze table has a single row when bidder numbers are frozen,
+-- i.e., when materials showing them have been printed.  While they are
+-- frozen, bidder numbers are not assigned or changed automatically.
+CREATE TABLE bidderFreeze (
+    -- Always 1, so that there is at most one row.
+    id integer PRIMARY KEY CHECK (id = 1),
+
+    -- Time the bidder numbers were frozen (seconds since epoch).
+    frozen integer NOT NULL
+);
+
+-- The bidderScheme table has a single row, naming the bidder numbering scheme
+-- (see the bidderNumbering setting in config.json) under which the current
+-- bidder numbers were assigned.  When the setting changes, everyone is given a
+-- new bidder number under the new scheme.  If there is no row, the numbers were
+-- assigned under the "hex" scheme.
+CREATE TABLE bidderScheme (
+    -- Always 1, so that there is at most one row.
+    id integer PRIMARY KEY CHECK (id = 1),
+
+    -- Name of the scheme.
+    name text NOT NULL
+);
+
+-- The bidderSnapshot table has a row for each guest who existed when bidder
+-- numbers were last frozen, recording what was printed for them, so that we
+-- can tell which materials need to be reprinted.
+CREATE TABLE bidderSnapshot (
+    -- Guest ID.  This is not a foreign key, since the snapshot needs to
+    -- remember guests who have since been deleted.
+    guest integer PRIMARY KEY,
+
+    -- Bidder number, name, and table number of the guest when frozen.
+    bidder integer NOT NULL,
+    name   text    NOT NULL,
+    tnum   integer NOT NULL
+);
+
+-- The entree table has a row for each entree on the dinner menu.
+CREATE TABLE entree (
+    -- Unique identifier of the entree.
+    id integer PRIMARY KEY,
+
+    -- Code for the entree, as stored in guest.entree and as submitted by the
+    -- registration form on the public web site.
+    code text NOT NULL UNIQUE
+        CHECK (code != ''),
+
+    -- Name of the entree (as it should appear in emails and reports).
+    name text NOT NULL
+        CHECK (name != ''),
+
+    -- Single letter identifying the entree on program labels, or empty if the
+    -- entree should not be shown on them.
+    label text NOT NULL DEFAULT ''
+        CHECK (length(label) <= 1),
+
+    -- Whether the entree can be chosen.  Guests who chose an entree before it
+    -- was made inactive keep their choice.
+    active boolean NOT NULL DEFAULT 1
+);
+INSERT INTO entree (code, name, label) VALUES
+    ('filet',  'Filet Mignon', 'M'),
+    ('salmon', 'Salmon',       'F'),
+    ('vegan',  'Vegan',        'V');
+
 -- The item table has a row for each thing that can be purchased or donated at
 -- the gala: essentially each registration type, each auction item, and each
 -- fund-a-need level.
@@ -119,9 +237,30 @@ CREATE TABLE item (
     -- Unique identifier of the item.
     id integer PRIMARY KEY,
 
+    -- Type of the item: "registration" (a gala registration), "silent" or
+    -- "live" (an auction item), "fundANeed" (a fund-a-need level), "donation"
+    -- (any other donation), or "raffle" (a raffle ticket).  Registrations,
+    -- donations, and raffle tickets don't need to be picked up, and only
+    -- auction items appear on the list of auction winners.
+    type text NOT NULL DEFAULT 'silent'
+        CHECK (type IN ('registration', 'silent', 'live', 'fundANeed', 'donation', 'raffle')),
+
+    -- Lot number of the item in the auction catalog (e.g. "S12"), or empty if
+    -- it has none.
+    lot text NOT NULL DEFAULT '',
+
     -- Name of the item (as it should appear on receipts and in the GUI).
     name text NOT NULL,
 
+    -- Catalog description of the item.
+    description text NOT NULL DEFAULT '',
+
+    -- Name of the donor of the item, as it should be credited in the catalog.
+    donor text NOT NULL DEFAULT '',
+
+    -- Restrictions on the use of the item (e.g. blackout dates, expiration).
+    restrictions text NOT NULL DEFAULT '',
+
     -- Amount to be paid by the purchaser, in cents, if that is a fixed price
     -- (e.g. for an item representing a fund-a-need level).  If the amount is
     -- not a fixed price (e.g. a silent auction item whose amount will be the
@@ -133,8 +272,9 @@ CREATE TABLE item (
     -- are purely donations (e.g. fund-a-need levels).
     value integer NOT NULL DEFAULT 0
 );
-INSERT INTO item (id, name, amount, value) VALUES
-    (1, 'Registration', 17500, 5000);
+CREATE UNIQUE INDEX item_lot_idx ON item (lot) WHERE lot != '';
+INSERT INTO item (id, type, name, amount, value) VALUES
+    (1, 'registration', 'Registration', 17500, 5000);
 
 -- The purchase table has a row for each purchase of an item.
 CREATE TABLE purchase (
@@ -181,7 +321,7 @@ CREATE TABLE purchase (
     unbid boolean NOT NULL DEFAULT 0,
 
     -- Flag for whether the item has been picked up (e.g., at check-out) or
-    -- otherwise redeemed.  Not relevant for donations (i.e., item.value=0).
+    -- otherwise redeemed.  Not relevant for registrations, donations, and raffle tickets.
     pickedUp boolean NOT NULL DEFAULT 0,
 
     -- Flag for whether the item was paid for by someone other than nominal
@@ -205,6 +345,10 @@ CREATE TABLE user (
     password text NOT NULL
 );
 INSERT INTO user VALUES (1, 'sroth', '$2a$10$rfRymy4A0lsILBJN6U4r4.qhzsktWGAOl2NIACJJvyLQOO4uLmI0m');
+-- The patron user is the identity under which changes made by patrons through
+-- the guest portal are journaled.  Its password is not in bcrypt format, so no
+-- one can log in as it.
+INSERT INTO user VALUES (2, 'patron', '*');
 
 -- The session table has one row for each valid session token.
 CREATE TABLE session (
@@ -220,15 +364,147 @@ CREATE TABLE session (
 CREATE INDEX session_user_idx    ON session (user);
 CREATE INDEX session_expires_idx ON session (expires);
 
+-- The portalToken table has one row for each link to the guest portal that has
+-- been issued to a host.  The portal lets the host fill in the details of the
+-- guests in their party.
+CREATE TABLE portalToken (
+    -- Portal token (a random string appearing at the end of the link).
+    token text PRIMARY KEY,
+
+    -- Identifier of the guest (the host) to whom the link was issued.  There
+    -- is at most one token per host.
+    guest integer NOT NULL UNIQUE REFERENCES guest ON DELETE CASCADE,
+
+    -- Time when the token was issued (seconds since epoch).
+    created integer NOT NULL
+);
+
+-- The emailTemplate table has one row for each email template that has been
+-- edited by staff.  Templates without a row here use the defaults built into
+-- the server (email/templates).
+CREATE TABLE emailTemplate (
+    -- Name of the template (e.g. "registration").
+    name text PRIMARY KEY,
+
+    -- Subject line of the email, as a Go text/template.
+    subject text NOT NULL,
+
+    -- Plain text form of the email body, as a Go text/template.  May be empty,
+    -- in which case the email is sent in HTML form only.
+    text text NOT NULL DEFAULT '',
+
+    -- HTML form of the email body, as a Go html/template.  May be empty, in
+    -- which case the email is sent in plain text form only.
+    html text NOT NULL DEFAULT ''
+);
+
+-- The labelTemplate table has one row for each label sheet template that has
+-- been defined or edited by staff.  Templates without a row here use the
+-- defaults built into the server (labels/templates.go), which cover the
+-- common Avery sheets.
+CREATE TABLE labelTemplate (
+    -- Name of the template (e.g. "avery5160").
+    name text PRIMARY KEY,
+
+    -- Page size, label layout, and fields of the template, as a JSON-encoded
+    -- object (see model.LabelTemplate).
+    spec text NOT NULL -- JSON
+);
+
+-- The email table has one row for each outgoing email message.  Messages are
+-- added to this table in the same transaction as the change that prompted
+-- them, and are delivered by a background worker, with retries.  The rows are
+-- retained after delivery as a history of the emails sent.
+CREATE TABLE email (
+    -- Unique identifier of the message.
+    id integer PRIMARY KEY,
+
+    -- Guest to whom the message was sent, or NULL if it was not sent to a
+    -- guest (or the guest has since been deleted).
+    guest integer REFERENCES guest ON DELETE SET NULL,
+
+    -- Name of the email template from which the message was generated.
+    template text NOT NULL,
+
+    -- Recipient(s) of the message (i.e., the content of its To: header).
+    recipient text NOT NULL,
+
+    -- Subject of the message.
+    subject text NOT NULL,
+
+    -- The complete message, with headers, ready for sending.
+    message blob NOT NULL,
+
+    -- Delivery status of the message: "pending", "sent", or "failed".
+    -- Failed messages have exhausted their retries.
+    status text NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'sent', 'failed')),
+
+    -- Number of delivery attempts made so far.
+    attempts integer NOT NULL DEFAULT 0,
+
+    -- Time of the next delivery attempt, for pending messages (seconds since
+    -- epoch).
+    nextAttempt integer NOT NULL DEFAULT 0,
+
+    -- Error from the most recent failed delivery attempt, if any.
+    lastError text NOT NULL DEFAULT '',
+
+    -- Time the message was queued (seconds since epoch).
+    created integer NOT NULL,
+
+    -- Time the message was delivered (seconds since epoch), or zero if it
+    -- hasn't been.
+    sent integer NOT NULL DEFAULT 0
+);
+CREATE INDEX email_guest_idx  ON email (guest);
+CREATE INDEX email_status_idx ON email (status, nextAttempt);
+
+-- The receiptSent table has one row for each payer whose year-end receipt has
+-- been emailed to them.  It records which version of the receipt they were
+-- sent, so that a bulk send can skip payers whose receipts haven't changed.
+CREATE TABLE receiptSent (
+    -- Payer to whom the receipt was sent.
+    payer integer PRIMARY KEY REFERENCES guest ON DELETE CASCADE,
+
+    -- Hash of the content of the receipt that was sent.
+    hash text NOT NULL,
+
+    -- Email message in which the receipt was sent, or NULL if that message
+    -- has since been deleted.
+    email integer REFERENCES email ON DELETE SET NULL,
+
+    -- Time the receipt was queued for sending (seconds since epoch).
+    sent integer NOT NULL
+);
+
+-- The reminderSent table has one row for each host who has been sent a
+-- reminder to supply the missing names and entree choices of their guests.  It
+-- records how many they've been sent, so that no host is reminded too often.
+CREATE TABLE reminderSent (
+    -- Host to whom the reminders were sent.
+    host integer PRIMARY KEY REFERENCES guest ON DELETE CASCADE,
+
+    -- Number of reminders sent to the host.
+    count integer NOT NULL,
+
+    -- Email message in which the latest reminder was sent, or NULL if that
+    -- message has since been deleted.
+    email integer REFERENCES email ON DELETE SET NULL,
+
+    -- Time the latest reminder was queued for sending (seconds since epoch).
+    sent integer NOT NULL
+);
+
 -- The journal table has one row for each transaction that changes the bidder,
--- group, guest, item, purchase, or payment tables.
+-- entree, group, guest, item, purchase, or payment tables.
 CREATE TABLE journal (
     -- Unique identifier (and sequence number) of the journal entry.
     id integer PRIMARY KEY,
 
     -- Username of the user who made the change journaled in this entry.  This
     -- may be NULL if the change was a patron registering through the public
-    -- site.
+    -- site, or "patron" if it was made through the guest portal.
     user text REFERENCES user (username),
 
     -- Timestamp of the change, in RFC3339 format.
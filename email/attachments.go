@@ -0,0 +1,162 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/letterhead"
+	"github.com/scholacantorum/gala-backend/sendmail"
+)
+
+// calendarInvite returns an iCalendar attachment for the gala, or nil if the
+// gala's start and end times (galaStart and galaEnd, in RFC 3339 format) are
+// not configured.
+func calendarInvite() *sendmail.Attachment {
+	var (
+		start, end time.Time
+		buf        bytes.Buffer
+		err        error
+	)
+	if start, err = time.Parse(time.RFC3339, config.Get("galaStart")); err != nil {
+		return nil
+	}
+	if end, err = time.Parse(time.RFC3339, config.Get("galaEnd")); err != nil {
+		return nil
+	}
+	const stamp = "20060102T150405Z"
+	for _, line := range []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Schola Cantorum//Gala//EN",
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		"UID:gala-" + start.UTC().Format(stamp) + "@scholacantorum.org",
+		"DTSTAMP:" + time.Now().UTC().Format(stamp),
+		"DTSTART:" + start.UTC().Format(stamp),
+		"DTEND:" + end.UTC().Format(stamp),
+		"SUMMARY:" + icalEscape(config.Get("galaTitle")),
+		"LOCATION:" + icalEscape(config.Get("galaLocation")),
+		"END:VEVENT",
+		"END:VCALENDAR",
+	} {
+		icalFold(&buf, line)
+	}
+	return &sendmail.Attachment{
+		Filename:    "gala.ics",
+		ContentType: "text/calendar; charset=UTF-8; method=PUBLISH",
+		Data:        buf.Bytes(),
+	}
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+// icalFold writes a content line, folding it to 75 octets per line as RFC 5545
+// requires, without splitting any UTF-8 sequence.
+func icalFold(buf *bytes.Buffer, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+// chargeReceiptPDF returns a PDF copy of a card charge receipt, suitable for
+// the recipient's tax records.
+func chargeReceiptPDF(data *chargeReceiptData) (*sendmail.Attachment, error) {
+	var (
+		buf    bytes.Buffer
+		widths []float64
+		heads  []string
+		aligns []string
+	)
+	pdf, tr := letterhead.New()
+	pdf.AddPage()
+	pdf.SetFont("helvetica", "B", 16)
+	pdf.CellFormat(letterhead.Width, 24, tr(fmt.Sprintf("Receipt for Order #%d", data.OrderID)), "", 1, "LT", false, 0, "")
+	pdf.SetFont("helvetica", "", 11)
+	pdf.Ln(6)
+	pdf.MultiCell(letterhead.Width, 14, tr(fmt.Sprintf("Dear %s,", data.Payer)), "", "L", false)
+	pdf.Ln(6)
+	pdf.MultiCell(letterhead.Width, 14, tr(fmt.Sprintf(
+		"We confirm the following purchases and donations made at %s on %s, charged to %s:",
+		data.EventTitle, data.EventDate, data.Card)), "", "L", false)
+	pdf.Ln(10)
+
+	// Lay out the columns, which vary in the same way as they do in the
+	// HTML email.
+	widths, heads, aligns = []float64{0}, []string{"Item"}, []string{"L"}
+	if data.MultipleBidders {
+		widths, heads, aligns = append(widths, 144), append(heads, "Bidder"), append(aligns, "L")
+	}
+	widths, heads, aligns = append(widths, 90), append(heads, "Amount Paid"), append(aligns, "R")
+	if data.TotalValue != 0 {
+		widths, heads, aligns = append(widths, 108), append(heads, "Estimated Value"), append(aligns, "R")
+	}
+	widths[0] = letterhead.Width
+	for _, w := range widths[1:] {
+		widths[0] -= w
+	}
+	pdf.SetFont("helvetica", "B", 11)
+	for i, h := range heads {
+		pdf.CellFormat(widths[i], 16, h, "B", 0, aligns[i], false, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("helvetica", "", 11)
+	for _, p := range data.Purchases {
+		cells := []string{tr(p.Item)}
+		if data.MultipleBidders {
+			cells = append(cells, tr(p.Bidder))
+		}
+		cells = append(cells, fmt.Sprintf("$%d", p.Amount))
+		if data.TotalValue != 0 {
+			cells = append(cells, fmt.Sprintf("$%d", p.Value))
+		}
+		for i, c := range cells {
+			pdf.CellFormat(widths[i], 16, c, "", 0, aligns[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	if len(data.Purchases) > 1 {
+		pdf.SetFont("helvetica", "B", 11)
+		label := widths[0]
+		if data.MultipleBidders {
+			label += widths[1]
+		}
+		pdf.CellFormat(label, 16, "TOTAL", "", 0, "R", false, 0, "")
+		pdf.CellFormat(90, 16, fmt.Sprintf("$%d", data.TotalAmount), "T", 0, "R", false, 0, "")
+		if data.TotalValue != 0 {
+			pdf.CellFormat(108, 16, fmt.Sprintf("$%d", data.TotalValue), "T", 0, "R", false, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("helvetica", "", 11)
+	}
+	pdf.Ln(14)
+	statement := "Schola Cantorum is a 501(c)(3) tax-exempt organization.  Our federal tax ID number is 94-2597822."
+	if data.TotalValue == 0 {
+		statement += "  No goods or services were provided in return for your donation."
+	}
+	pdf.MultiCell(letterhead.Width, 14, statement, "", "L", false)
+	pdf.Ln(6)
+	pdf.MultiCell(letterhead.Width, 14, "Thank you for your support of Schola Cantorum!", "", "L", false)
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return &sendmail.Attachment{
+		Filename:    fmt.Sprintf("receipt-%d.pdf", data.OrderID),
+		ContentType: "application/pdf",
+		Data:        buf.Bytes(),
+	}, nil
+}
@@ -0,0 +1,167 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+	"github.com/scholacantorum/gala-backend/sendmail"
+)
+
+// previewers is the set of functions that generate a preview of each template
+// for a given guest.
+var previewers = map[string]func(*sqlx.Tx, *model.Guest) (*sendmail.Message, error){
+	"chargeReceipt":  previewChargeReceipt,
+	"registration":   previewRegistration,
+	"reminder":       previewReminder,
+	"yearEndReceipt": previewYearEndReceipt,
+}
+
+// templateInfo is the JSON representation of an email template.
+type templateInfo struct {
+	model.EmailTemplate
+	Customized bool `json:"customized"`
+}
+
+// ServeEmails handles requests starting with /emails.
+func ServeEmails(w *request.ResponseWriter, r *request.Request) {
+	var (
+		head string
+		name string
+	)
+	name, r.URL.Path = request.ShiftPath(r.URL.Path)
+	if name == "" {
+		serveEmails(w, r)
+		return
+	}
+	if defaultTemplate(name) == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	switch head {
+	case "":
+		serveEmail(w, r, name)
+	case "preview":
+		servePreview(w, r, name)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveEmails handles GET /emails, which returns all email templates.
+func serveEmails(w *request.ResponseWriter, r *request.Request) {
+	var templates []*templateInfo
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	for _, name := range templateNames() {
+		templates = append(templates, getTemplateInfo(r, name))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// serveEmail handles requests to /emails/${name}.
+func serveEmail(w *request.ResponseWriter, r *request.Request, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(getTemplateInfo(r, name))
+	case http.MethodPut:
+		saveTemplate(w, r, name)
+	case http.MethodDelete:
+		(&model.EmailTemplate{Name: name}).Delete(r.Tx)
+		w.CommitNoContent(r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func getTemplateInfo(r *request.Request, name string) *templateInfo {
+	if t := model.FetchEmailTemplate(r.Tx, name); t != nil {
+		return &templateInfo{*t, true}
+	}
+	return &templateInfo{*defaultTemplate(name), false}
+}
+
+// saveTemplate handles a PUT /emails/${name} request.  The template is parsed
+// before being saved, and parse errors are returned as 400 errors.
+func saveTemplate(w *request.ResponseWriter, r *request.Request, name string) {
+	var (
+		body model.EmailTemplate
+		err  error
+	)
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("saveTemplate JSON decode %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.Name != name || body.Subject == "" || (body.Text == "" && body.HTML == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if _, err = parseTemplate(&body); err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err)
+		return
+	}
+	body.Save(r.Tx)
+	w.CommitNoContent(r)
+}
+
+// servePreview handles GET /emails/${name}/preview?guest=${gid}.  It renders
+// the named template against the data for the specified guest, and returns
+// the resulting message without sending it.
+func servePreview(w *request.ResponseWriter, r *request.Request, name string) {
+	var (
+		gid     int
+		guest   *model.Guest
+		message *sendmail.Message
+		err     error
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if gid, err = strconv.Atoi(r.FormValue("guest")); err != nil || gid < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if guest = model.FetchGuest(r.Tx, db.ID(gid)); guest == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if message, err = previewers[name](r.Tx, guest); err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err)
+		return
+	}
+	attachments := []string{}
+	for _, a := range message.Attachments {
+		attachments = append(attachments, a.Filename)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"to":          message.To,
+		"subject":     message.Subject,
+		"text":        message.Text,
+		"html":        message.HTML,
+		"attachments": attachments,
+	})
+}
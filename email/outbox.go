@@ -0,0 +1,189 @@
+package email
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+	"github.com/scholacantorum/gala-backend/sendmail"
+)
+
+const (
+	// pollInterval is how often the sender checks for messages to deliver.
+	pollInterval = 15 * time.Second
+	// firstRetry is the delay before the first retry of a failed delivery.
+	// It doubles with each successive failure, up to maxRetry.
+	firstRetry = time.Minute
+	maxRetry   = 4 * time.Hour
+	// maxAttempts is the number of delivery attempts made before a message
+	// is marked as failed.
+	maxAttempts = 10
+)
+
+// Queue adds a message to the outgoing email queue.  It is sent by the
+// background sender after the transaction is committed.  guest is the guest to
+// whom the message is being sent (or zero if none), and template is the name
+// of the template from which it was generated.
+func Queue(tx *sqlx.Tx, guest db.ID, template string, message *sendmail.Message) *model.Email {
+	var e = model.Email{
+		GuestID:   guest,
+		Template:  template,
+		Recipient: strings.Join(message.To, ", "),
+		Subject:   message.Subject,
+		Message:   message.Render(),
+		Status:    model.EmailPending,
+		Created:   db.Time{Time: time.Now()},
+	}
+	e.NextAttempt = e.Created
+	e.Save(tx)
+	return &e
+}
+
+// Sender is a goroutine that delivers queued email messages.  It uses the
+// database only while holding lock, which must be the lock that serializes
+// request handling, so that requests wait for it rather than failing on a busy
+// database.  The lock is not held while talking to the mail server.
+func Sender(dbh *sqlx.DB, lock sync.Locker) {
+	for {
+		deliverPending(dbh, lock)
+		time.Sleep(pollInterval)
+	}
+}
+
+// deliverPending attempts delivery of all pending messages whose time has come.
+// It catches any panic, so that a database error doesn't take down the server.
+func deliverPending(dbh *sqlx.DB, lock sync.Locker) {
+	var (
+		pending []*model.Email
+		err     error
+	)
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("ERROR: email sender: %v", panicked)
+			log.Print(string(debug.Stack()))
+		}
+	}()
+	if pending, err = fetchPending(dbh, lock); err != nil {
+		log.Printf("ERROR: email sender: %s", err)
+		return
+	}
+	// Each delivery gets its own transaction, so that we aren't holding the
+	// database while talking to the mail server.
+	for _, e := range pending {
+		sendErr := sendmail.Send(e.Message)
+		if err = saveAttempt(dbh, lock, e, sendErr); err != nil {
+			log.Printf("ERROR: email sender: %s", err)
+			return
+		}
+	}
+}
+
+// fetchPending returns the pending messages whose time has come.
+func fetchPending(dbh *sqlx.DB, lock sync.Locker) (pending []*model.Email, err error) {
+	var tx *sqlx.Tx
+
+	lock.Lock()
+	defer lock.Unlock()
+	if tx, err = dbh.Beginx(); err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	model.FetchEmails(tx, func(e *model.Email) {
+		var copy = *e
+		pending = append(pending, &copy)
+	}, `status=? AND nextAttempt<=?`, model.EmailPending, time.Now().Unix())
+	return pending, nil
+}
+
+// saveAttempt records the result of a delivery attempt in its own transaction.
+func saveAttempt(dbh *sqlx.DB, lock sync.Locker, e *model.Email, sendErr error) (err error) {
+	var tx *sqlx.Tx
+
+	lock.Lock()
+	defer lock.Unlock()
+	if tx, err = dbh.Beginx(); err != nil {
+		return err
+	}
+	defer tx.Rollback() // if recordAttempt panics
+	recordAttempt(tx, e, sendErr)
+	return tx.Commit()
+}
+
+// recordAttempt records the result of a delivery attempt.
+func recordAttempt(tx *sqlx.Tx, e *model.Email, err error) {
+	var now = time.Now()
+
+	e.Attempts++
+	if err == nil {
+		e.Status = model.EmailSent
+		e.Sent = db.Time{Time: now}
+		e.LastError = ""
+		e.Save(tx)
+		return
+	}
+	log.Printf("ERROR: sending email %d to %s (attempt %d): %s", e.ID, e.Recipient, e.Attempts, err)
+	e.LastError = err.Error()
+	if e.Attempts >= maxAttempts {
+		e.Status = model.EmailFailed
+	} else {
+		e.NextAttempt = db.Time{Time: now.Add(min(firstRetry<<(e.Attempts-1), maxRetry))}
+	}
+	e.Save(tx)
+}
+
+// ServeEmail handles requests starting with /email.
+func ServeEmail(w *request.ResponseWriter, r *request.Request) {
+	var (
+		head string
+		eid  int
+		e    *model.Email
+		err  error
+	)
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	if eid, err = strconv.Atoi(head); err != nil || eid < 1 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if e = model.FetchEmail(r.Tx, db.ID(eid)); e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	switch head {
+	case "resend":
+		serveResend(w, r, e)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveResend handles POST /email/${eid}/resend.  It queues a new copy of the
+// message, leaving the original in the history.
+func serveResend(w *request.ResponseWriter, r *request.Request, e *model.Email) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	e.ID = 0
+	e.Status = model.EmailPending
+	e.Attempts = 0
+	e.LastError = ""
+	e.Created = db.Time{Time: time.Now()}
+	e.NextAttempt = e.Created
+	e.Sent = db.Time{}
+	e.Save(r.Tx)
+	w.CommitNoContent(r)
+}
@@ -0,0 +1,115 @@
+package email
+
+import (
+	"errors"
+	"log"
+	"net/mail"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/sendmail"
+)
+
+// chargeReceiptData is the data passed to the "chargeReceipt" template.
+type chargeReceiptData struct {
+	OrderID         int
+	Payer           string
+	EventTitle      string
+	EventDate       string
+	Card            string
+	MultipleBidders bool
+	TotalValue      int
+	TotalAmount     int
+	Deductible      int
+	Purchases       []chargeReceiptPurchase
+}
+type chargeReceiptPurchase struct {
+	Item   string
+	Bidder string
+	Amount int
+	Value  int
+}
+
+// ChargeReceiptMessage returns the receipt email for a card charge, with
+// order number onum, to the specified payer for the specified purchases.
+func ChargeReceiptMessage(
+	tx *sqlx.Tx, onum int, payer *model.Guest, purchases []*model.Purchase,
+) (message *sendmail.Message, err error) {
+	var (
+		data chargeReceiptData
+		addr mail.Address
+	)
+
+	// Fill in the template data.
+	data.OrderID = onum
+	data.Payer = payer.Name
+	data.EventTitle = config.Get("galaTitle")
+	data.EventDate = config.Get("galaDate")
+	data.Card = payer.StripeDescription
+	data.Purchases = make([]chargeReceiptPurchase, len(purchases))
+	for i, p := range purchases {
+		item := model.FetchItem(tx, p.ItemID)
+		data.Purchases[i] = chargeReceiptPurchase{
+			Item:   item.Name,
+			Bidder: model.FetchGuest(tx, p.GuestID).Name,
+			Amount: p.Amount / 100,
+			Value:  item.Value / 100,
+		}
+		if item.Value > p.Amount {
+			data.Purchases[i].Value = p.Amount / 100
+		}
+		if data.Purchases[i].Bidder != data.Purchases[0].Bidder {
+			data.MultipleBidders = true
+		}
+		data.TotalAmount += data.Purchases[i].Amount
+		data.TotalValue += data.Purchases[i].Value
+	}
+	data.Deductible = data.TotalAmount - data.TotalValue
+
+	// Start the email.
+	message = new(sendmail.Message)
+	message.From = "Schola Cantorum <admin@scholacantorum.org>"
+	addr.Name = payer.Name
+	addr.Address = payer.Email
+	message.To = []string{addr.String()}
+	message.Bcc = strings.Split(config.Get("emailTo"), ",")
+	message.ReplyTo = "Schola Cantorum <info@scholacantorum.org>"
+	message.Images = [][]byte{sendmail.ScholaLogoPNG}
+	if err = render(tx, "chargeReceipt", &data, message); err != nil {
+		return nil, err
+	}
+	// A failure to generate the PDF copy shouldn't stop the receipt from
+	// being sent.
+	if pdf, err := chargeReceiptPDF(&data); err == nil {
+		message.Attachments = append(message.Attachments, *pdf)
+	} else {
+		log.Printf("ERROR: PDF receipt for order %d: %s", onum, err)
+	}
+	return message, nil
+}
+
+// previewChargeReceipt generates the receipt email for the most recent card
+// charge paid by the specified guest.
+func previewChargeReceipt(tx *sqlx.Tx, payer *model.Guest) (*sendmail.Message, error) {
+	var (
+		onum      int
+		purchases []*model.Purchase
+	)
+	model.FetchPurchases(tx, func(p *model.Purchase) {
+		if p.ScholaOrder > onum {
+			onum = p.ScholaOrder
+		}
+	}, `payer=? AND item NOT IN (SELECT id FROM item WHERE type=?) AND paymentTimestamp!=''`,
+		payer.ID, model.ItemRegistration)
+	if onum == 0 {
+		return nil, errors.New("guest has no card charges")
+	}
+	model.FetchPurchases(tx, func(p *model.Purchase) {
+		var copy = *p
+		purchases = append(purchases, &copy)
+	}, `payer=? AND scholaOrder=?`, payer.ID, onum)
+	return ChargeReceiptMessage(tx, onum, payer, purchases)
+}
@@ -0,0 +1,144 @@
+package email
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/sendmail"
+)
+
+// RegistrationOrder describes the order through which a set of guests were
+// registered.
+type RegistrationOrder struct {
+	ID    int
+	Name  string
+	Email string
+	Card  string
+	Total int // cents
+	Date  time.Time
+}
+
+// registrationData is the data passed to the "registration" template.
+type registrationData struct {
+	OrderID     int
+	Name        string
+	Guests      []registrationGuest
+	NumberWidth int // width of Number column in plain text, with padding
+	NameWidth   int // width of Name column in plain text, with padding
+	Requests    string
+	Missing     bool
+	Deadline    string // for guest names and entree choices; empty if none
+	PortalLink  string // link to the guest portal, if there is one
+	Total       int    // dollars
+	Date        string
+	Card        string
+}
+type registrationGuest struct {
+	Number string
+	Name   string
+	Entree string
+}
+
+// RegistrationMessage returns the registration confirmation email for the
+// specified order and guests.  missing indicates whether any of the guests'
+// names or entree choices are yet to be supplied.
+func RegistrationMessage(
+	tx *sqlx.Tx, order *RegistrationOrder, guests []*model.Guest, missing bool,
+) (message *sendmail.Message, err error) {
+	var (
+		addr mail.Address
+		data = registrationData{
+			OrderID:   order.ID,
+			Name:      order.Name,
+			NameWidth: utf8.RuneCountInString("Guest Name") + 2,
+			Missing:   missing,
+			Total:     order.Total / 100,
+			Date:      order.Date.Format("January 2, 2006"),
+			Card:      order.Card,
+		}
+	)
+	for i, g := range guests {
+		rg := registrationGuest{Number: fmt.Sprintf("%d.", i+1), Name: g.Name, Entree: entreeName(tx, g.Entree)}
+		data.Guests = append(data.Guests, rg)
+		data.NumberWidth = max(data.NumberWidth, utf8.RuneCountInString(rg.Number)+2)
+		data.NameWidth = max(data.NameWidth, utf8.RuneCountInString(rg.Name)+2)
+	}
+	if deadline := InfoDeadline(); !deadline.IsZero() {
+		data.Deadline = deadline.Format("January 2")
+	}
+	if len(guests) != 0 {
+		data.Requests = guests[0].Requests
+		data.PortalLink = model.PortalLink(tx, guests[0].ID)
+	}
+	message = new(sendmail.Message)
+	message.From = "Schola Cantorum <admin@scholacantorum.org>"
+	addr.Name = order.Name
+	addr.Address = order.Email
+	message.To = []string{addr.String()}
+	message.Bcc = []string{"admin@scholacantorum.org"}
+	if bcc := config.Get("receiptBCC"); bcc != "" {
+		message.Bcc = append(message.Bcc, strings.Split(bcc, ",")...)
+	}
+	message.Images = [][]byte{sendmail.ScholaLogoPNG}
+	if err = render(tx, "registration", &data, message); err != nil {
+		return nil, err
+	}
+	if invite := calendarInvite(); invite != nil {
+		message.Attachments = append(message.Attachments, *invite)
+	}
+	return message, nil
+}
+
+// previewRegistration generates a registration confirmation email for the
+// party hosted by the specified guest, reconstructing the order from the
+// guest's registration purchases.
+func previewRegistration(tx *sqlx.Tx, host *model.Guest) (*sendmail.Message, error) {
+	var (
+		order   = RegistrationOrder{Name: host.Name, Email: host.Email}
+		guests  = []*model.Guest{host}
+		missing bool
+	)
+	model.FetchPurchases(tx, func(p *model.Purchase) {
+		if order.ID == 0 && order.Card == "" {
+			order.ID = p.ScholaOrder
+			order.Card = p.PaymentDescription
+			if ts, err := time.Parse(time.RFC3339, p.PaymentTimestamp); err == nil {
+				order.Date = ts
+			}
+		}
+		if p.ScholaOrder == order.ID && p.PaymentDescription == order.Card {
+			order.Total += p.Amount
+		}
+	}, `item IN (SELECT id FROM item WHERE type=?) AND payer=?`, model.ItemRegistration, host.ID)
+	if order.Date.IsZero() {
+		order.Date = time.Now()
+	}
+	model.FetchGuestsInParty(tx, host.PartyID, func(g *model.Guest) {
+		if g.ID != host.ID {
+			var copy = *g
+			guests = append(guests, &copy)
+		}
+	})
+	for _, g := range guests {
+		if g.MissingInfo() != nil {
+			missing = true
+		}
+	}
+	return RegistrationMessage(tx, &order, guests, missing)
+}
+
+// entreeName returns the name of the entree with the specified code, as it
+// should appear in the email.
+func entreeName(tx *sqlx.Tx, code string) string {
+	if code == "" {
+		return "(not yet selected)"
+	}
+	return model.EntreeName(tx, code)
+}
@@ -0,0 +1,109 @@
+package email
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/sendmail"
+)
+
+// reminderData is the data passed to the "reminder" template.
+type reminderData struct {
+	Host       string
+	EventTitle string
+	EventDate  string
+	Deadline   string // empty if none
+	Guests     []reminderGuest
+	PortalLink string // link to the guest portal, if there is one
+}
+type reminderGuest struct {
+	Number  string
+	Name    string // empty if not yet known
+	Missing string // e.g. "name and entree choice"
+}
+
+// InfoDeadline returns the deadline for hosts to supply their guests' names
+// and entree choices, from the infoDeadline setting (RFC 3339) in config.json.
+// It returns the zero time if there is none.
+func InfoDeadline() (deadline time.Time) {
+	deadline, _ = time.Parse(time.RFC3339, config.Get("infoDeadline"))
+	return deadline
+}
+
+// ReminderGuests returns the guests registered by the specified host (i.e.,
+// whose registrations the host paid for) whose names or entree choices are
+// still missing.  Cancelled guests are omitted.
+func ReminderGuests(tx *sqlx.Tx, host *model.Guest) (guests []*model.Guest) {
+	var (
+		ids  []db.ID
+		seen = make(map[db.ID]bool)
+	)
+	model.FetchPurchases(tx, func(p *model.Purchase) {
+		if !seen[p.GuestID] {
+			seen[p.GuestID] = true
+			ids = append(ids, p.GuestID)
+		}
+	}, `item IN (SELECT id FROM item WHERE type=?) AND payer=?`, model.ItemRegistration, host.ID)
+	for _, id := range ids {
+		if g := model.FetchGuest(tx, id); g != nil && !g.Cancelled && g.MissingInfo() != nil {
+			guests = append(guests, g)
+		}
+	}
+	return guests
+}
+
+// ReminderMessage returns the email reminding the host to supply the missing
+// information for the specified guests.
+func ReminderMessage(tx *sqlx.Tx, host *model.Guest, guests []*model.Guest) (message *sendmail.Message, err error) {
+	var (
+		addr mail.Address
+		data = reminderData{
+			Host:       host.Name,
+			EventTitle: config.Get("galaTitle"),
+			EventDate:  config.Get("galaDate"),
+			PortalLink: model.PortalLink(tx, host.ID),
+		}
+	)
+	if deadline := InfoDeadline(); !deadline.IsZero() {
+		data.Deadline = deadline.Format("Monday, January 2")
+	}
+	for i, g := range guests {
+		rg := reminderGuest{Number: fmt.Sprintf("%d.", i+1), Name: g.Name, Missing: strings.Join(g.MissingInfo(), " and ")}
+		if g.HasPlaceholderName() {
+			rg.Name = ""
+		}
+		data.Guests = append(data.Guests, rg)
+	}
+	message = new(sendmail.Message)
+	message.From = "Schola Cantorum <admin@scholacantorum.org>"
+	addr.Name = host.Name
+	addr.Address = host.Email
+	message.To = []string{addr.String()}
+	message.ReplyTo = "Schola Cantorum <info@scholacantorum.org>"
+	message.Images = [][]byte{sendmail.ScholaLogoPNG}
+	if err = render(tx, "reminder", &data, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// previewReminder generates the reminder email for the specified host.  If
+// nothing is missing for the host's guests, the host's own entry is shown as
+// an example.
+func previewReminder(tx *sqlx.Tx, host *model.Guest) (*sendmail.Message, error) {
+	var guests = ReminderGuests(tx, host)
+
+	if len(guests) == 0 {
+		example := *host
+		example.Entree = ""
+		guests = []*model.Guest{&example}
+	}
+	return ReminderMessage(tx, host, guests)
+}
@@ -0,0 +1,122 @@
+// Package email handles the content of the email messages sent by the gala
+// server.  Each message is built from a named template, with a subject line, a
+// plain text body, and an HTML body.  The defaults for the templates are built
+// into the server (in the templates directory); staff can override them
+// through the /emails API, in which case the overrides are stored in the
+// database.
+package email
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/sendmail"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// templateNames returns the names of all known email templates, in sorted
+// order.
+func templateNames() (names []string) {
+	entries, err := defaultTemplates.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".subject"); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultTemplate returns the built-in default for the named template, or nil
+// if there is no such template.
+func defaultTemplate(name string) (t *model.EmailTemplate) {
+	var (
+		subject []byte
+		text    []byte
+		html    []byte
+		err     error
+	)
+	if subject, err = defaultTemplates.ReadFile("templates/" + name + ".subject"); err != nil {
+		return nil
+	}
+	if text, err = defaultTemplates.ReadFile("templates/" + name + ".txt"); err != nil {
+		panic(err)
+	}
+	if html, err = defaultTemplates.ReadFile("templates/" + name + ".html"); err != nil {
+		panic(err)
+	}
+	return &model.EmailTemplate{Name: name, Subject: string(subject), Text: string(text), HTML: string(html)}
+}
+
+// loadTemplate returns the named template: the staff-edited version if there
+// is one, or the default otherwise.  It returns nil if there is no such
+// template.
+func loadTemplate(tx *sqlx.Tx, name string) (t *model.EmailTemplate) {
+	if defaultTemplate(name) == nil {
+		return nil
+	}
+	if t = model.FetchEmailTemplate(tx, name); t != nil {
+		return t
+	}
+	return defaultTemplate(name)
+}
+
+// parsed is a template set that has been parsed and is ready for execution.
+type parsed struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// parseTemplate parses the three parts of an email template.
+func parseTemplate(t *model.EmailTemplate) (p parsed, err error) {
+	if p.subject, err = texttemplate.New("subject").Parse(t.Subject); err != nil {
+		return p, err
+	}
+	if p.text, err = texttemplate.New("text").Parse(t.Text); err != nil {
+		return p, err
+	}
+	if p.html, err = htmltemplate.New("html").Parse(t.HTML); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// render renders the named template with the supplied data, filling in the
+// Subject, Text, and HTML of the supplied message.
+func render(tx *sqlx.Tx, name string, data interface{}, message *sendmail.Message) (err error) {
+	var (
+		p   parsed
+		buf bytes.Buffer
+	)
+	if p, err = parseTemplate(loadTemplate(tx, name)); err != nil {
+		return err
+	}
+	if err = p.subject.Execute(&buf, data); err != nil {
+		return err
+	}
+	message.Subject = strings.TrimSpace(buf.String())
+	buf.Reset()
+	if err = p.text.Execute(&buf, data); err != nil {
+		return err
+	}
+	message.Text = buf.String()
+	buf.Reset()
+	if err = p.html.Execute(&buf, data); err != nil {
+		return err
+	}
+	message.HTML = buf.String()
+	return nil
+}
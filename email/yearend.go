@@ -0,0 +1,57 @@
+package email
+
+import (
+	"net/mail"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/sendmail"
+)
+
+// yearEndReceiptData is the data passed to the "yearEndReceipt" template.
+type yearEndReceiptData struct {
+	Payer      string
+	EventTitle string
+	EventDate  string
+}
+
+// YearEndReceiptMessage returns the email that sends the specified payer
+// their year-end receipt.  pdf is the receipt itself, which is attached to the
+// message unless it is nil.
+func YearEndReceiptMessage(tx *sqlx.Tx, payer *model.Guest, pdf []byte) (message *sendmail.Message, err error) {
+	var (
+		addr mail.Address
+		data = yearEndReceiptData{
+			Payer:      payer.Name,
+			EventTitle: config.Get("galaTitle"),
+			EventDate:  config.Get("galaDate"),
+		}
+	)
+	message = new(sendmail.Message)
+	message.From = "Schola Cantorum <admin@scholacantorum.org>"
+	addr.Name = payer.Name
+	addr.Address = payer.Email
+	message.To = []string{addr.String()}
+	message.ReplyTo = "Schola Cantorum <info@scholacantorum.org>"
+	message.Images = [][]byte{sendmail.ScholaLogoPNG}
+	if err = render(tx, "yearEndReceipt", &data, message); err != nil {
+		return nil, err
+	}
+	if pdf != nil {
+		message.Attachments = append(message.Attachments, sendmail.Attachment{
+			Filename:    "gala-receipt.pdf",
+			ContentType: "application/pdf",
+			Data:        pdf,
+		})
+	}
+	return message, nil
+}
+
+// previewYearEndReceipt generates the year-end receipt email for the specified
+// guest.  The receipt itself is not generated for the preview, so the preview
+// has no attachment.
+func previewYearEndReceipt(tx *sqlx.Tx, payer *model.Guest) (*sendmail.Message, error) {
+	return YearEndReceiptMessage(tx, payer, nil)
+}
@@ -0,0 +1,118 @@
+package entree
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// ServeEntree handles requests starting with /entree.
+func ServeEntree(w *request.ResponseWriter, r *request.Request) {
+	var (
+		head   string
+		eid    int
+		entree *model.Entree
+		err    error
+	)
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	if eid, err = strconv.Atoi(head); err != nil || eid < 1 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if entree = model.FetchEntree(r.Tx, db.ID(eid)); entree == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	switch head {
+	case "":
+		serveEntree(w, r, entree)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveEntree handles requests to /entree/${eid}.
+func serveEntree(w *request.ResponseWriter, r *request.Request, entree *model.Entree) {
+	switch r.Method {
+	case http.MethodDelete:
+		deleteEntree(w, r, entree)
+	case http.MethodPut:
+		saveEntree(w, r, entree)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// deleteEntree handles a DELETE /entree/${eid} request.  Entrees that have been
+// chosen by any guest can't be deleted; they should be made inactive instead.
+func deleteEntree(w *request.ResponseWriter, r *request.Request, entree *model.Entree) {
+	var (
+		chosen bool
+		je     model.JournalEntry
+	)
+	model.FetchGuests(r.Tx, func(g *model.Guest) { chosen = true }, `entree=?`, entree.Code)
+	if chosen {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	entree.Delete(r.Tx, &je)
+	journal.Log(r, &je)
+	w.CommitNoContent(r)
+}
+
+// saveEntree handles a PUT /entree/${eid} request.  If the entree's code is
+// changed, the guests who chose it are updated to match.
+func saveEntree(w *request.ResponseWriter, r *request.Request, entree *model.Entree) {
+	var (
+		body   model.Entree
+		guests []*model.Guest
+		je     model.JournalEntry
+		err    error
+	)
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("saveEntree JSON decode %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.ID != entree.ID || !validEntree(r, &body) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.Code != entree.Code {
+		model.FetchGuests(r.Tx, func(g *model.Guest) {
+			var copy = *g
+			guests = append(guests, &copy)
+		}, `entree=?`, entree.Code)
+	}
+	entree.Code = body.Code
+	entree.Name = body.Name
+	entree.Label = body.Label
+	entree.Active = body.Active
+	entree.Save(r.Tx, &je)
+	for _, g := range guests {
+		g.Entree = entree.Code
+		g.Save(r.Tx, &je)
+	}
+	journal.Log(r, &je)
+	w.CommitNoContent(r)
+}
+
+// validEntree returns whether the entree is valid for saving: it must have a
+// code and name, its label must be at most one letter, and its code must not
+// be in use by another entree.
+func validEntree(r *request.Request, e *model.Entree) bool {
+	if e.Code == "" || e.Name == "" || len(e.Label) > 1 {
+		return false
+	}
+	if other := model.FetchEntreeByCode(r.Tx, e.Code); other != nil && other.ID != e.ID {
+		return false
+	}
+	return true
+}
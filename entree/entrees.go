@@ -0,0 +1,56 @@
+package entree
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// ServeEntrees handles requests starting with /entrees.
+func ServeEntrees(w *request.ResponseWriter, r *request.Request) {
+	var (
+		head string
+	)
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	switch head {
+	case "":
+		serveEntrees(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveEntrees handles requests to /entrees.
+func serveEntrees(w *request.ResponseWriter, r *request.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		addEntree(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// addEntree handles a POST /entrees request.
+func addEntree(w *request.ResponseWriter, r *request.Request) {
+	var (
+		body model.Entree
+		je   model.JournalEntry
+		err  error
+	)
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("addEntree JSON decode %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.ID != 0 || !validEntree(r, &body) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	body.Save(r.Tx, &je)
+	journal.Log(r, &je)
+	w.CommitNoContent(r)
+}
@@ -3,6 +3,7 @@ module github.com/scholacantorum/gala-backend
 go 1.24.1
 
 require (
+	github.com/boombuler/barcode v1.1.0
 	github.com/gorilla/websocket v1.5.3
 	github.com/jmoiron/sqlx v1.4.0
 	github.com/jung-kurt/gofpdf v1.16.2
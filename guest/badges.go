@@ -0,0 +1,116 @@
+package guest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/labels"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// nameBadges are the guests' name badges.  Each badge gets the guest's first
+// name in large type, their full name, their table and bidder numbers, a QR
+// code for looking them up at check-in, and their ribbon (if any) on a black
+// band.  By default they are printed onto 3" x 4" badge inserts.
+var nameBadges = labels.Kind{
+	ConfigKey: "badgeTemplate",
+	Template:  "avery5392",
+	Fields: []model.LabelField{
+		{Text: "{{.firstName}}", Size: 40, Bold: true, Align: "C"},
+		{Text: "{{.name}}", Size: 16, Align: "C", Height: 24},
+		{Text: "{{with .table}}Table {{.}}{{end}}", Size: 14, Align: "C"},
+		{Text: "{{with .bidder}}Bidder {{.}}{{end}}", Size: 14, Align: "C"},
+		{Text: "{{.code}}", Size: 48, Align: "C", QR: true},
+		{Text: "{{.ribbon}}", Size: 14, Bold: true, Align: "C", Height: 24, Banner: true},
+	},
+}
+
+// serveBadges handles GET /guests/badges.  It returns a PDF of name badges for
+// the guests at numbered tables, in alphabetical order.  Cancelled guests are
+// omitted.  The badges can be limited with query parameters:
+//
+//	guest=ID    only the specified guest(s), whether or not they are seated
+//	            or cancelled (e.g. for walk-ins at the door)
+//	table=N     only the guests at table number N
+//	since=TIME  only the guests changed since TIME (RFC 3339)
+//
+// Guests whose names are placeholders get badges with blank names, to be
+// filled in by hand.  The "template" query parameter selects the label
+// template; the default is set by the badgeTemplate setting in config.json.
+func serveBadges(w *request.ResponseWriter, r *request.Request) {
+	var (
+		ids     map[db.ID]bool
+		tnum    = -1
+		changed map[db.ID]bool
+		guests  []*model.Guest
+		sheet   []labels.Label
+		err     error
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	if len(r.Form["guest"]) != 0 {
+		ids = make(map[db.ID]bool)
+		for _, idstr := range r.Form["guest"] {
+			id, err := strconv.Atoi(idstr)
+			if err != nil || id < 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ids[db.ID(id)] = true
+		}
+	}
+	if tstr := r.FormValue("table"); tstr != "" {
+		if tnum, err = strconv.Atoi(tstr); err != nil || tnum < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if since := r.FormValue("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		changed = journal.GuestsChangedSince(r.Tx, t)
+	}
+	model.FetchGuests(r.Tx, func(g *model.Guest) {
+		var copy = *g
+		guests = append(guests, &copy)
+	}, "")
+	sort.Slice(guests, func(i, j int) bool { return guests[i].Sortname < guests[j].Sortname })
+	for _, g := range guests {
+		table := model.FetchTable(r.Tx, model.FetchParty(r.Tx, g.PartyID).TableID)
+		switch {
+		case ids != nil && !ids[g.ID]:
+			continue
+		case ids == nil && (g.Cancelled || table.Number == 0):
+			continue
+		case tnum != -1 && table.Number != tnum:
+			continue
+		case changed != nil && !changed[g.ID]:
+			continue
+		}
+		label := guestLabel(r.Tx, g)
+		if g.HasPlaceholderName() {
+			label["name"], label["firstName"] = "", ""
+		}
+		sheet = append(sheet, label)
+	}
+	if ids != nil && len(sheet) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	nameBadges.Serve(w, r, "badges.pdf", sheet)
+}
@@ -0,0 +1,229 @@
+package guest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// bidderChange describes a guest whose printed materials need to be reprinted
+// because their bidder number, name, or table changed since bidder numbers
+// were frozen.  Added guests have no Old values; deleted guests have no New
+// ones.
+type bidderChange struct {
+	Guest     db.ID    `json:"guest"`
+	Change    string   `json:"change"` // "added", "deleted", or "changed"
+	Fields    []string `json:"fields"` // "bidder", "name", and/or "table"
+	OldBidder string   `json:"oldBidder"`
+	OldName   string   `json:"oldName"`
+	OldTable  int      `json:"oldTable"`
+	Bidder    string   `json:"bidder"`
+	Name      string   `json:"name"`
+	Table     int      `json:"table"`
+}
+
+// serveBidderFreeze handles requests to /guests/bidder-freeze.  GET returns
+// {"frozen": time} (or null if bidder numbers aren't frozen); POST freezes
+// bidder numbers, taking a new snapshot of what is about to be printed; DELETE
+// unfreezes them.
+func serveBidderFreeze(w *request.ResponseWriter, r *request.Request) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var result struct {
+			Frozen *db.Time `json:"frozen"`
+		}
+		if frozen := model.BiddersFrozen(r.Tx); !frozen.IsZero() {
+			result.Frozen = &frozen
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(&result)
+	case http.MethodPost:
+		model.FreezeBidders(r.Tx)
+		w.CommitNoContent(r)
+	case http.MethodDelete:
+		model.UnfreezeBidders(r.Tx)
+		w.CommitNoContent(r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// fetchBidderChanges compares the guests to the snapshot taken when bidder
+// numbers were last frozen, and returns the guests whose bidder numbers,
+// names, or tables have changed, sorted by name.  Guests added since the
+// snapshot are included only if they have a bidder number or table.
+func fetchBidderChanges(tx *sqlx.Tx) (changes []*bidderChange) {
+	var (
+		snapshots = make(map[db.ID]*model.BidderSnapshot)
+		tables    = make(map[db.ID]int)
+	)
+	changes = []*bidderChange{}
+	model.FetchBidderSnapshots(tx, func(bs *model.BidderSnapshot) {
+		var copy = *bs
+		snapshots[bs.GuestID] = &copy
+	})
+	model.FetchTables(tx, func(t *model.Table) { tables[t.ID] = t.Number }, "")
+	model.FetchGuests(tx, func(g *model.Guest) {
+		var (
+			bs     = snapshots[g.ID]
+			table  = tables[model.FetchParty(tx, g.PartyID).TableID]
+			change = bidderChange{Guest: g.ID, Bidder: model.FormatBidder(g.Bidder), Name: g.Name, Table: table}
+		)
+		delete(snapshots, g.ID)
+		if bs == nil {
+			if g.Bidder != 0 || table != 0 {
+				change.Change = "added"
+				changes = append(changes, &change)
+			}
+			return
+		}
+		change.OldBidder, change.OldName, change.OldTable = model.FormatBidder(bs.Bidder), bs.Name, bs.Table
+		if bs.Bidder != g.Bidder {
+			change.Fields = append(change.Fields, "bidder")
+		}
+		if bs.Name != g.Name {
+			change.Fields = append(change.Fields, "name")
+		}
+		if bs.Table != table {
+			change.Fields = append(change.Fields, "table")
+		}
+		if len(change.Fields) != 0 {
+			change.Change = "changed"
+			changes = append(changes, &change)
+		}
+	}, "")
+	for _, bs := range snapshots {
+		changes = append(changes, &bidderChange{
+			Guest: bs.GuestID, Change: "deleted",
+			OldBidder: model.FormatBidder(bs.Bidder), OldName: bs.Name, OldTable: bs.Table,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changeName(changes[i]) < changeName(changes[j])
+	})
+	return changes
+}
+
+func changeName(c *bidderChange) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.OldName
+}
+
+// serveBidderChanges handles GET /guests/bidder-changes.  It returns a JSON
+// array of the guests whose bidder numbers, names, or tables have changed
+// since bidder numbers were last frozen.
+func serveBidderChanges(w *request.ResponseWriter, r *request.Request) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(fetchBidderChanges(r.Tx))
+}
+
+// serveBidderChangesPDF handles GET /guests/bidder-changes.pdf.  It returns the
+// same report as a PDF, as a checklist of the materials to reprint.
+func serveBidderChangesPDF(w *request.ResponseWriter, r *request.Request) {
+	const (
+		left       = 54.0
+		width      = 504.0
+		colWidth   = 216.0
+		lineHeight = 14.0
+	)
+	var (
+		pdf     *gofpdf.Fpdf
+		tr      func(string) string
+		buf     bytes.Buffer
+		frozen  db.Time
+		changes []*bidderChange
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	frozen = model.BiddersFrozen(r.Tx)
+	changes = fetchBidderChanges(r.Tx)
+	pdf = gofpdf.New("P", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(left, 54, left)
+	pdf.SetAutoPageBreak(true, 54)
+	pdf.AddPage()
+	pdf.SetFont("helvetica", "B", 16)
+	pdf.CellFormat(width, 22, tr(fmt.Sprintf("%s — Bidder Changes", config.Get("galaTitle"))), "", 1, "L", false, 0, "")
+	pdf.SetFont("helvetica", "", 11)
+	if frozen.IsZero() {
+		pdf.CellFormat(width, 16, "Changes since bidder numbers were last frozen (now unfrozen)", "", 1, "L", false, 0, "")
+	} else {
+		pdf.CellFormat(width, 16, "Changes since bidder numbers were frozen at "+frozen.Format("Jan 2 3:04pm"), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(8)
+	if len(changes) == 0 {
+		pdf.SetFont("helvetica", "I", 11)
+		pdf.CellFormat(width, lineHeight, "Nothing needs to be reprinted.", "", 1, "L", false, 0, "")
+	} else {
+		pdf.SetFont("helvetica", "B", 11)
+		pdf.CellFormat(width-2*colWidth, lineHeight, "Change", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(colWidth, lineHeight, "Was", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(colWidth, lineHeight, "Now", "B", 1, "L", false, 0, "")
+		pdf.SetFont("helvetica", "", 10)
+		for _, c := range changes {
+			what := c.Change
+			if c.Change == "changed" {
+				what = strings.Join(c.Fields, ", ")
+			}
+			pdf.CellFormat(width-2*colWidth, lineHeight, what, "", 0, "L", false, 0, "")
+			pdf.CellFormat(colWidth, lineHeight, tr(describeBidder(c.OldName, c.OldBidder, c.OldTable)), "", 0, "L", false, 0, "")
+			pdf.CellFormat(colWidth, lineHeight, tr(describeBidder(c.Name, c.Bidder, c.Table)), "", 1, "L", false, 0, "")
+		}
+	}
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="bidder-changes.pdf"`)
+	w.Write(buf.Bytes())
+}
+
+// describeBidder returns a one-line description of a guest for the bidder
+// changes report, e.g. "Jane Doe #12A, table 12".
+func describeBidder(name, bidder string, table int) string {
+	if name == "" {
+		return "—"
+	}
+	if bidder != "" {
+		name += " #" + bidder
+	}
+	if table != 0 {
+		name += ", table " + strconv.Itoa(table)
+	}
+	return name
+}
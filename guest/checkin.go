@@ -5,10 +5,12 @@ import (
 	"log"
 	"net/http"
 	"sort"
+	"strings"
 
 	"github.com/jung-kurt/gofpdf"
 
 	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/qrcode"
 	"github.com/scholacantorum/gala-backend/request"
 )
 
@@ -123,7 +125,27 @@ func renderGuest(pdf *gofpdf.Fpdf, r *request.Request, logo, banner *gofpdf.Imag
 	pdf.Cell(324, 20, "Postal")
 	pdf.MoveTo(36+offset, 513)
 	pdf.CellFormat(324, 20, "Thank you for supporting Schola Cantorum!", "", 0, "TC", false, 0, "")
+	renderNeeds(pdf, guest, offset)
 	pdf.MoveTo(36+offset, 573)
-	pdf.SetFontSize(12)
-	pdf.Cell(324, 20, fmt.Sprintf("Table %d Bidder %x", table.Number, guest.Bidder))
+	pdf.SetFont("helvetica", "", 12)
+	pdf.Cell(264, 20, fmt.Sprintf("Table %d Bidder %s", table.Number, model.FormatBidder(guest.Bidder)))
+	// The QR code lets volunteers pull up the guest's party by scanning the
+	// form rather than searching for the name.
+	qrcode.Draw(pdf, guest.Code(), 300+offset, 533, 60)
+}
+
+// renderNeeds adds the guest's dietary restrictions and accessibility needs,
+// if any, to the check-in form, so that they can be confirmed with the guest.
+func renderNeeds(pdf *gofpdf.Fpdf, guest *model.Guest, offset float64) {
+	var tr = pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("helvetica", "I", 10)
+	if needs := guest.DietaryNeeds(); len(needs) != 0 {
+		pdf.MoveTo(36+offset, 539)
+		pdf.CellFormat(264, 12, tr("Dietary: "+strings.Join(needs, "; ")), "", 0, "TL", false, 0, "")
+	}
+	if needs := guest.AccessNeeds(); len(needs) != 0 {
+		pdf.MoveTo(36+offset, 553)
+		pdf.CellFormat(264, 12, tr("Access: "+strings.Join(needs, "; ")), "", 0, "TL", false, 0, "")
+	}
 }
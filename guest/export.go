@@ -3,7 +3,6 @@ package guest
 import (
 	"encoding/csv"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/scholacantorum/gala-backend/model"
@@ -24,12 +23,8 @@ func serveGuestList(w *request.ResponseWriter, r *request.Request) {
 	cw.UseCRLF = true
 	cw.Write([]string{"Bidder", "Guest", "Email", "Address", "City", "State", "Zip", "Phone", "Entree", "Requests"})
 	model.FetchGuests(r.Tx, func(g *model.Guest) {
-		fields := []string{"", g.Sortname, g.Email, g.Address, g.City, g.State, g.Zip, g.Phone, g.Entree,
-			strings.ReplaceAll(g.Requests, "\n", " ")}
-		if g.Bidder != 0 {
-			fields[0] = strconv.FormatInt(int64(g.Bidder), 16)
-		}
-		cw.Write(fields)
+		cw.Write([]string{model.FormatBidder(g.Bidder), g.Sortname, g.Email, g.Address, g.City, g.State, g.Zip, g.Phone,
+			model.EntreeName(r.Tx, g.Entree), strings.ReplaceAll(g.Requests, "\n", " ")})
 	}, "1 ORDER BY sortname")
 	cw.Flush()
 }
@@ -37,6 +37,14 @@ func ServeGuest(w *request.ResponseWriter, r *request.Request) {
 	switch head {
 	case "":
 		serveGuest(w, r, guest)
+	case "emails":
+		serveGuestEmails(w, r, guest)
+	case "portal-link":
+		serveGuestPortalLink(w, r, guest)
+	case "receipt.pdf":
+		serveGuestReceiptPDF(w, r, guest)
+	case "seat":
+		serveGuestSeat(w, r, guest)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -94,6 +102,8 @@ func saveGuest(w *request.ResponseWriter, r *request.Request, guest *model.Guest
 		errmsg        string
 		status        int
 		pfid          db.ID
+		before        map[db.ID]int
+		warning       string
 		err           error
 		bodyPayingFor = map[db.ID]bool{}
 	)
@@ -139,6 +149,10 @@ func saveGuest(w *request.ResponseWriter, r *request.Request, guest *model.Guest
 			}
 		}
 	}
+	if !model.ValidEntreeChoice(r.Tx, body.Entree, guest.Entree) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 	if body.Address == "" || body.City == "" || body.State == "" || body.Zip == "" {
 		body.Address, body.City, body.State, body.Zip = "", "", "", "" // all or none
 	}
@@ -171,6 +185,7 @@ func saveGuest(w *request.ResponseWriter, r *request.Request, guest *model.Guest
 	}
 
 	// Update the database and generate the journal.
+	before = model.SeatingCounts(r.Tx)
 	if body.Name != guest.Name {
 		// preserve the unusual sortname of "John Doe Guest #1"
 		guest.Sortname = sortname(body.Name)
@@ -187,7 +202,14 @@ func saveGuest(w *request.ResponseWriter, r *request.Request, guest *model.Guest
 	guest.Bidder = body.Bidder
 	guest.PayerID = body.PayerID
 	guest.Entree = body.Entree
+	guest.Allergies = body.Allergies
+	guest.GlutenFree = body.GlutenFree
+	guest.Vegetarian = body.Vegetarian
+	guest.Wheelchair = body.Wheelchair
+	guest.HearingAssist = body.HearingAssist
 	guest.Notes = body.Notes
+	guest.Cancelled = body.Cancelled
+	guest.Ribbon = body.Ribbon
 	guest.Save(r.Tx, &je)
 	model.FetchGuests(r.Tx, func(g *model.Guest) {
 		if !bodyPayingFor[g.ID] && g.PayerID == guest.ID {
@@ -204,15 +226,41 @@ func saveGuest(w *request.ResponseWriter, r *request.Request, guest *model.Guest
 		party.TableID = body.TableID
 		party.Save(r.Tx, &je)
 	}
+	party := model.FetchParty(r.Tx, guest.PartyID)
+	if warning = model.OverfillWarning(r.Tx, before, party.TableID); warning != "" && model.RefuseOverfill() {
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, warning)
+		return
+	}
 	if body.X != 0 || body.Y != 0 {
-		party := model.FetchParty(r.Tx, guest.PartyID)
 		table := model.FetchTable(r.Tx, party.TableID)
 		table.X = body.X
 		table.Y = body.Y
 		table.Save(r.Tx, &je)
 	}
 	journal.Log(r, &je)
-	w.CommitNoContent(r)
+	w.CommitWarning(r, warning)
+}
+
+// serveGuestEmails handles GET /guest/${gid}/emails.  It returns the history
+// of emails sent (or queued to be sent) to the guest.
+func serveGuestEmails(w *request.ResponseWriter, r *request.Request, guest *model.Guest) {
+	var emails = []*model.Email{}
+
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	model.FetchEmails(r.Tx, func(e *model.Email) {
+		var copy = *e
+		emails = append(emails, &copy)
+	}, `guest=?`, guest.ID)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(emails)
 }
 
 func addPayingForPurchases(w *request.ResponseWriter, r *request.Request, payer *model.Guest, purchases []db.ID) {
@@ -301,3 +349,37 @@ func UpdateCustomer(guest *model.Guest, name, email, card string) (status int, e
 	}
 	return resp.StatusCode, string(by)
 }
+
+// stripeUpdate is a change to the name or email address of a guest who is a
+// Stripe customer, to be made in Stripe once the database changes are made.
+type stripeUpdate struct {
+	label string       // identifies the update in error messages, if needed
+	old   *model.Guest // as it was before the change
+	guest *model.Guest // as it is after the change
+}
+
+// updateStripeCustomers makes a set of changes to Stripe customers.  It is
+// called after all of the database changes have been made, just before they
+// are committed.  If any update fails, it undoes the updates already made (as
+// best it can) and returns the failing status and an error message; otherwise,
+// it returns 200.
+func updateStripeCustomers(updates []*stripeUpdate) (status int, errmsg string) {
+	for i, u := range updates {
+		if status, errmsg = UpdateCustomer(u.old, u.guest.Name, u.guest.Email, ""); status == 200 {
+			continue
+		}
+		if errmsg == "" {
+			errmsg = "the Stripe customer could not be updated"
+		}
+		for _, done := range updates[:i] {
+			if st, msg := UpdateCustomer(done.old, done.old.Name, done.old.Email, ""); st != 200 {
+				log.Printf("ERROR: can't restore Stripe customer for guest %d: %d %s", done.old.ID, st, msg)
+			}
+		}
+		if u.label != "" {
+			errmsg = u.label + ": " + errmsg
+		}
+		return status, errmsg
+	}
+	return 200, ""
+}
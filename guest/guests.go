@@ -25,14 +25,32 @@ func ServeGuests(w *request.ResponseWriter, r *request.Request) {
 	switch head {
 	case "":
 		serveGuests(w, r)
+	case "badges":
+		serveBadges(w, r)
+	case "bidder-changes":
+		serveBidderChanges(w, r)
+	case "bidder-changes.pdf":
+		serveBidderChangesPDF(w, r)
+	case "bidder-freeze":
+		serveBidderFreeze(w, r)
 	case "checkin-forms":
 		serveCheckinForms(w, r)
+	case "import":
+		serveGuestImport(w, r)
 	case "list":
 		serveGuestList(w, r)
+	case "paddles.pdf":
+		servePaddles(w, r)
 	case "program-labels":
 		serveProgramLabels(w, r)
 	case "receipts":
 		serveReceipts(w, r)
+	case "receipts.pdf":
+		serveReceiptsPDF(w, r)
+	case "reminders":
+		serveReminders(w, r)
+	case "scan":
+		serveScan(w, r)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -61,6 +79,7 @@ func addGuest(w *request.ResponseWriter, r *request.Request) {
 		body          addGuestBody
 		je            model.JournalEntry
 		purchase      model.Purchase
+		regItem       *model.Item
 		pfid          db.ID
 		err           error
 		bodyPayingFor = map[db.ID]bool{}
@@ -96,6 +115,15 @@ func addGuest(w *request.ResponseWriter, r *request.Request) {
 			return
 		}
 	}
+	if !model.ValidEntreeChoice(r.Tx, body.Entree, "") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if regItem = model.FetchRegistrationItem(r.Tx); regItem == nil {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, "there is no registration item")
+		return
+	}
 	if body.Address == "" || body.City == "" || body.State == "" || body.Zip == "" {
 		body.Address, body.City, body.State, body.Zip = "", "", "", "" // all or none
 	}
@@ -149,8 +177,8 @@ func addGuest(w *request.ResponseWriter, r *request.Request) {
 	purchase = model.Purchase{
 		GuestID: body.ID,
 		PayerID: body.ID,
-		ItemID:  1,
-		Amount:  model.FetchItem(r.Tx, 1).Amount,
+		ItemID:  regItem.ID,
+		Amount:  regItem.Amount,
 	}
 	if body.Ticket != "" {
 		purchase.PaymentTimestamp = time.Now().Format(time.RFC3339)
@@ -0,0 +1,443 @@
+package guest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+	"github.com/scholacantorum/gala-backend/spreadsheet"
+)
+
+// guestImportFields are the fields that can be imported from a guest roster.
+// The flags (glutenFree through hearingAssist) take yes/no values.  The
+// ticket is the payment description for the registrations (e.g. "Sponsor
+// comp"), as in addGuest.
+var guestImportFields = []spreadsheet.Field{
+	{Name: "party", Headers: []string{"group", "table", "sponsor"}},
+	{Name: "name", Headers: []string{"guest", "guest name", "full name"}},
+	{Name: "email", Headers: []string{"email address", "e-mail"}},
+	{Name: "phone", Headers: []string{"phone number", "telephone"}},
+	{Name: "address", Headers: []string{"street", "street address"}},
+	{Name: "city"},
+	{Name: "state"},
+	{Name: "zip", Headers: []string{"zip code", "postal code"}},
+	{Name: "entree", Headers: []string{"entrée", "meal", "dinner"}},
+	{Name: "allergies", Headers: []string{"dietary restrictions"}},
+	{Name: "glutenFree", Headers: []string{"gf"}},
+	{Name: "vegetarian", Headers: []string{"veg"}},
+	{Name: "wheelchair"},
+	{Name: "hearingAssist", Headers: []string{"hearing assistance"}},
+	{Name: "requests", Headers: []string{"special requests", "seating requests"}},
+	{Name: "notes"},
+	{Name: "ticket", Headers: []string{"payment"}},
+}
+
+// Guest import row actions.
+const (
+	importCreate    = "create"
+	importUpdate    = "update"
+	importUnchanged = "unchanged"
+	importError     = "error"
+)
+
+// guestImportResult is the response to POST /guests/import.
+type guestImportResult struct {
+	DryRun  bool              `json:"dryRun"`
+	Columns map[string]string `json:"columns"` // field name → column header
+	Rows    []*guestImportRow `json:"rows"`
+	Errors  int               `json:"errors"`          // number of rows with errors
+	Error   string            `json:"error,omitempty"` // problem with the file as a whole
+}
+
+// guestImportRow is the result of importing one row of a guest roster.
+type guestImportRow struct {
+	Row      int      `json:"row"` // row number in the file
+	Party    string   `json:"party"`
+	Name     string   `json:"name"`
+	Action   string   `json:"action"`
+	Guest    db.ID    `json:"guest"`    // existing guest matched, or new guest created
+	Changes  []string `json:"changes"`  // names of the fields changed
+	Register bool     `json:"register"` // whether a registration is added
+	Errors   []string `json:"errors"`
+	ticket   string
+	guest    *model.Guest // with the changes applied
+}
+
+// guestImportGroup is the set of rows going into one party.  The first row is
+// the host, who pays for the registrations added by the import.
+type guestImportGroup struct {
+	rows []*guestImportRow
+}
+
+// serveGuestImport handles POST /guests/import, which imports a guest roster
+// (e.g. a sponsor's table) from a CSV or XLSX spreadsheet.  The request is a
+// multipart form with these fields:
+//
+//	file     the spreadsheet
+//	columns  optional JSON object mapping field names to column headers;
+//	         fields not mapped are found by their usual headers
+//	dryRun   if true, only validate and report what would be done
+//
+// Each row is matched against the existing guests by name, or by email address
+// if it has no name (see guestMatcher.match).  Matched guests are updated with
+// the non-blank cells of their row; other rows create new guests.  Rows with
+// the same party value (or all rows, if there is no party column) form a
+// group.  The new guests of a group join the party of the first matched guest
+// in it, or a new party if there is none; matched guests stay in their
+// parties.  The first row of each group is its host, who is the payer for the
+// registrations added for the new guests (and for matched guests who aren't
+// yet registered).  Rows without a name get placeholder names, like addGuest's.
+//
+// The result reports the action taken (or to be taken) on each row, and the
+// errors found.  If any row has an error, nothing is imported and the status
+// is 400 (unless it is a dry run).  Otherwise, everything is imported in a
+// single journaled transaction.  Changes to the names and email addresses of
+// Stripe customers are made in Stripe only after the rest of the import has
+// been saved; if one fails, nothing is imported.
+func serveGuestImport(w *request.ResponseWriter, r *request.Request) {
+	var (
+		result  guestImportResult
+		sheet   *spreadsheet.Sheet
+		mapping map[string]string
+		columns map[string]int
+		groups  []*guestImportGroup
+		regItem = model.FetchRegistrationItem(r.Tx)
+		updates []*stripeUpdate
+		je      model.JournalEntry
+		err     error
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	result.DryRun = spreadsheet.Flag(r.FormValue("dryRun"))
+	result.Rows = []*guestImportRow{}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		result.Error = "no spreadsheet file was uploaded"
+		sendGuestImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	defer file.Close()
+	if sheet, err = spreadsheet.Read(file); err != nil {
+		result.Error = err.Error()
+		sendGuestImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	if cstr := r.FormValue("columns"); cstr != "" {
+		if err = json.Unmarshal([]byte(cstr), &mapping); err != nil {
+			result.Error = "invalid column mapping: " + err.Error()
+			sendGuestImportResult(w, http.StatusBadRequest, &result)
+			return
+		}
+	}
+	if columns, err = sheet.Columns(guestImportFields, mapping); err != nil {
+		result.Error = err.Error()
+		sendGuestImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	result.Columns = make(map[string]string)
+	for field, col := range columns {
+		result.Columns[field] = sheet.Header[col]
+	}
+	if _, ok := columns["name"]; !ok {
+		result.Error = "no column of guest names was found"
+		sendGuestImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	groups = planGuestImport(r.Tx, sheet, columns, &result)
+	if regItem == nil {
+		for _, ir := range result.Rows {
+			if ir.Register {
+				result.Error = "there is no registration item to register the guests with"
+				sendGuestImportResult(w, http.StatusBadRequest, &result)
+				return
+			}
+		}
+	}
+	if result.DryRun {
+		sendGuestImportResult(w, http.StatusOK, &result)
+		return
+	}
+	if result.Errors != 0 {
+		sendGuestImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	for _, group := range groups {
+		updates = append(updates, applyGuestImport(r.Tx, group, regItem, &je)...)
+	}
+	if status, errmsg := updateStripeCustomers(updates); status != 200 {
+		for _, ir := range result.Rows {
+			if ir.Action == importCreate {
+				ir.Guest = 0 // not created after all
+			}
+		}
+		result.Error = errmsg
+		sendGuestImportResult(w, status, &result)
+		return
+	}
+	journal.Log(r, &je)
+	if err = r.Tx.Commit(); err != nil {
+		panic(err)
+	}
+	sendGuestImportResult(w, http.StatusOK, &result)
+}
+
+// sendGuestImportResult sends the result of a guest import.
+func sendGuestImportResult(w *request.ResponseWriter, status int, result *guestImportResult) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// guestMatcher finds the existing guests that match the rows of a roster.
+type guestMatcher struct {
+	byEmail map[string][]*model.Guest
+	byName  map[string][]*model.Guest
+}
+
+func newGuestMatcher(tx *sqlx.Tx) (gm *guestMatcher) {
+	gm = &guestMatcher{byEmail: make(map[string][]*model.Guest), byName: make(map[string][]*model.Guest)}
+	model.FetchGuests(tx, func(g *model.Guest) {
+		var copy = *g
+		if g.Email != "" {
+			gm.byEmail[strings.ToLower(g.Email)] = append(gm.byEmail[strings.ToLower(g.Email)], &copy)
+		}
+		gm.byName[strings.ToLower(g.Name)] = append(gm.byName[strings.ToLower(g.Name)], &copy)
+	}, "")
+	return gm
+}
+
+// match returns the existing guest with the specified name, using the email
+// address to choose among guests with the same name.  Only if no name is given
+// does it match by email address alone.  (Rosters often give the sponsor's
+// email address for every guest, so a named guest who isn't already known is
+// a new guest, not the sponsor.)  It returns nil if there is no match, and an
+// error message if there is more than one.
+func (gm *guestMatcher) match(email, name string) (*model.Guest, string) {
+	var matches []*model.Guest
+
+	if name != "" {
+		matches = gm.byName[strings.ToLower(name)]
+	} else if email != "" {
+		matches = gm.byEmail[strings.ToLower(email)]
+	}
+	if len(matches) > 1 && name != "" && email != "" {
+		var same []*model.Guest
+		for _, m := range matches {
+			if strings.EqualFold(m.Email, email) {
+				same = append(same, m)
+			}
+		}
+		if len(same) != 0 {
+			matches = same
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ""
+	case 1:
+		return matches[0], ""
+	default:
+		var ids []string
+		for _, m := range matches {
+			ids = append(ids, fmt.Sprint(m.ID))
+		}
+		return nil, fmt.Sprintf("matches more than one existing guest (IDs %s)", strings.Join(ids, ", "))
+	}
+}
+
+// entreeCodes returns a map from the lower-cased codes and names of the
+// entrees to their codes, and the set of active entree codes.
+func entreeCodes(tx *sqlx.Tx) (codes map[string]string, active map[string]bool) {
+	codes, active = make(map[string]string), make(map[string]bool)
+	model.FetchEntrees(tx, func(e *model.Entree) {
+		codes[strings.ToLower(e.Code)] = e.Code
+		codes[strings.ToLower(e.Name)] = e.Code
+		active[e.Code] = e.Active
+	}, "")
+	return codes, active
+}
+
+// planGuestImport works out what importing each row of a roster would do,
+// and groups the rows into parties.  It changes nothing in the database.
+func planGuestImport(
+	tx *sqlx.Tx, sheet *spreadsheet.Sheet, columns map[string]int, result *guestImportResult,
+) (groups []*guestImportGroup) {
+	var (
+		matcher       = newGuestMatcher(tx)
+		codes, active = entreeCodes(tx)
+		byParty       = make(map[string]*guestImportGroup)
+		matchedRow    = make(map[db.ID]int)
+		placeholders  = make(map[*guestImportGroup]int)
+		registered    = make(map[db.ID]bool)
+	)
+	model.FetchPurchases(tx, func(p *model.Purchase) {
+		registered[p.GuestID] = true
+	}, `item IN (SELECT id FROM item WHERE type=?)`, model.ItemRegistration)
+	for i, row := range sheet.Rows {
+		var (
+			value = func(field string) string { return spreadsheet.Value(row, columns, field) }
+			ir    = guestImportRow{Row: sheet.Numbers[i], Party: value("party"), Name: value("name"), ticket: value("ticket")}
+			g     model.Guest
+		)
+		ir.Changes, ir.Errors = []string{}, []string{}
+		match, errmsg := matcher.match(value("email"), value("name"))
+		if errmsg != "" {
+			ir.Errors = append(ir.Errors, errmsg)
+		}
+		if match != nil {
+			if prev, ok := matchedRow[match.ID]; ok {
+				ir.Errors = append(ir.Errors, fmt.Sprintf("same guest as row %d", prev))
+			}
+			matchedRow[match.ID] = ir.Row
+			g = *match
+			ir.Guest = g.ID
+		}
+		if email := value("email"); email != "" && !strings.Contains(email, "@") {
+			ir.Errors = append(ir.Errors, fmt.Sprintf("%q is not a valid email address", email))
+		}
+		group := byParty[ir.Party]
+		if group == nil {
+			group = new(guestImportGroup)
+			byParty[ir.Party] = group
+			groups = append(groups, group)
+		}
+
+		// Apply the non-blank cells to the guest, noting the changes.
+		set := func(field string, into *string) {
+			if v := value(field); v != "" && v != *into {
+				*into = v
+				ir.Changes = append(ir.Changes, field)
+			}
+		}
+		flag := func(field string, into *bool) {
+			if v := value(field); v != "" && spreadsheet.Flag(v) != *into {
+				*into = spreadsheet.Flag(v)
+				ir.Changes = append(ir.Changes, field)
+			}
+		}
+		oldName := g.Name
+		set("name", &g.Name)
+		if g.Name != oldName {
+			g.Sortname = sortname(g.Name)
+		}
+		set("email", &g.Email)
+		set("phone", &g.Phone)
+		set("address", &g.Address)
+		set("city", &g.City)
+		set("state", &g.State)
+		set("zip", &g.Zip)
+		if v := value("entree"); v != "" {
+			code, ok := codes[strings.ToLower(v)]
+			if !ok || (!active[code] && code != g.Entree) {
+				ir.Errors = append(ir.Errors, fmt.Sprintf("%q is not one of the entrees on the menu", v))
+			} else if code != g.Entree {
+				g.Entree = code
+				ir.Changes = append(ir.Changes, "entree")
+			}
+		}
+		set("allergies", &g.Allergies)
+		flag("glutenFree", &g.GlutenFree)
+		flag("vegetarian", &g.Vegetarian)
+		flag("wheelchair", &g.Wheelchair)
+		flag("hearingAssist", &g.HearingAssist)
+		set("requests", &g.Requests)
+		set("notes", &g.Notes)
+		if g.Address != "" || g.City != "" || g.State != "" || g.Zip != "" {
+			if g.Address == "" || g.City == "" || g.State == "" || g.Zip == "" {
+				ir.Errors = append(ir.Errors, "the address needs a street, city, state, and zip")
+			}
+		}
+
+		// Guests without names get placeholders based on the host's name.
+		if g.Name == "" {
+			if len(group.rows) == 0 {
+				ir.Errors = append(ir.Errors, "the first guest of each party must have a name")
+			} else {
+				host := group.rows[0].guest
+				placeholders[group]++
+				g.Name = fmt.Sprintf("%s Guest #%d", host.Name, placeholders[group])
+				g.Sortname = fmt.Sprintf("%s Guest #%d", host.Sortname, placeholders[group])
+			}
+		}
+		ir.Name = g.Name
+		switch {
+		case len(ir.Errors) != 0:
+			ir.Action = importError
+			result.Errors++
+		case g.ID == 0:
+			ir.Action = importCreate
+		case len(ir.Changes) != 0:
+			ir.Action = importUpdate
+		default:
+			ir.Action = importUnchanged
+		}
+		ir.Register = !registered[g.ID]
+		ir.guest = &g
+		group.rows = append(group.rows, &ir)
+		result.Rows = append(result.Rows, &ir)
+	}
+	return groups
+}
+
+// applyGuestImport saves the guests of one party of a guest import, and adds
+// their registrations.  It returns the changes to be made to Stripe customers.
+func applyGuestImport(
+	tx *sqlx.Tx, group *guestImportGroup, regItem *model.Item, je *model.JournalEntry,
+) (updates []*stripeUpdate) {
+	var (
+		partyID db.ID
+		host    = group.rows[0].guest
+	)
+	for _, ir := range group.rows {
+		if ir.guest.ID != 0 {
+			partyID = ir.guest.PartyID
+			break
+		}
+	}
+	for _, ir := range group.rows {
+		g := ir.guest
+		if g.ID == 0 {
+			g.PartyID = partyID
+		}
+		// If the guest is a Stripe customer, note any necessary updates.
+		if old := model.FetchGuest(tx, g.ID); old != nil && old.StripeCustomer != "" &&
+			(old.Name != g.Name || old.Email != g.Email) {
+			updates = append(updates, &stripeUpdate{label: fmt.Sprintf("row %d", ir.Row), old: old, guest: g})
+		}
+		if ir.Action != importUnchanged {
+			g.Save(tx, je)
+		}
+		if partyID == 0 {
+			partyID = g.PartyID
+		}
+		ir.Guest = g.ID
+		if ir.Register {
+			purchase := model.Purchase{GuestID: g.ID, PayerID: host.ID, ItemID: regItem.ID, Amount: regItem.Amount}
+			ticket := ir.ticket
+			if ticket == "" {
+				ticket = group.rows[0].ticket
+			}
+			if ticket != "" {
+				purchase.PaymentTimestamp = time.Now().Format(time.RFC3339)
+				purchase.PaymentDescription = ticket
+			}
+			purchase.Save(tx, je)
+		}
+	}
+	return updates
+}
@@ -0,0 +1,55 @@
+package guest
+
+import (
+	"testing"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/db/dbtest"
+)
+
+func TestGuestMatcher(t *testing.T) {
+	tx := dbtest.OpenTx(t)
+	tx.MustExec(`INSERT INTO gtable (id) VALUES (1)`)
+	tx.MustExec(`INSERT INTO party (id, gtable) VALUES (1,1)`)
+	tx.MustExec(`
+INSERT INTO guest (id, name, sortname, email, party) VALUES
+    (1, 'Pat Sponsor', 'Sponsor, Pat', 'sponsor@corp.com',  1),
+    (2, 'Lee Chan',    'Chan, Lee',    'lee@example.com',   1),
+    (3, 'Lee Chan',    'Chan, Lee',    'other@example.com', 1),
+    (4, 'Sam Twin',    'Twin, Sam',    '',                  1),
+    (5, 'Sam Twin',    'Twin, Sam',    '',                  1),
+    (6, 'Kim Shared',  'Shared, Kim',  'shared@example.com', 1),
+    (7, 'Jo Shared',   'Shared, Jo',   'Shared@Example.com', 1)`)
+	gm := newGuestMatcher(tx)
+	tests := []struct {
+		email, name string
+		want        db.ID
+		err         bool
+	}{
+		{"", "Pat Sponsor", 1, false},
+		{"", "pat sponsor", 1, false},
+		{"sponsor@corp.com", "", 1, false},
+		{"SPONSOR@corp.com", "Pat Sponsor", 1, false},
+		// A new guest listed with the sponsor's email is not the sponsor.
+		{"sponsor@corp.com", "Jane Newguest", 0, false},
+		{"", "Jane Newguest", 0, false},
+		{"nobody@example.com", "", 0, false},
+		// The email chooses among guests with the same name.
+		{"other@example.com", "Lee Chan", 3, false},
+		{"", "Lee Chan", 0, true},
+		{"nobody@example.com", "Lee Chan", 0, true},
+		{"", "Sam Twin", 0, true},
+		{"shared@example.com", "", 0, true},
+		{"shared@example.com", "Jo Shared", 7, false},
+	}
+	for _, tt := range tests {
+		g, errmsg := gm.match(tt.email, tt.name)
+		var got db.ID
+		if g != nil {
+			got = g.ID
+		}
+		if got != tt.want || (errmsg != "") != tt.err {
+			t.Errorf("match(%q, %q) = %d, %q; want %d, error %v", tt.email, tt.name, got, errmsg, tt.want, tt.err)
+		}
+	}
+}
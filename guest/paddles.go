@@ -0,0 +1,162 @@
+package guest
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/qrcode"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// paddle is a single bid paddle.  Guests who share a bidder number (e.g. a
+// couple where one pays for the other) share a paddle.
+type paddle struct {
+	Bidder int
+	Table  int
+	Guests []*model.Guest // payer first
+}
+
+// fetchPaddles returns the bid paddles to be printed, sorted by table number
+// and then bidder number.  If changed is not nil, only the paddles for which
+// one of the guests sharing the bidder number is in changed are returned.
+func fetchPaddles(tx *sqlx.Tx, changed map[db.ID]bool) (paddles []*paddle) {
+	var (
+		byBidder = make(map[int]*paddle)
+		tables   = make(map[db.ID]int)
+	)
+	model.FetchTables(tx, func(t *model.Table) { tables[t.ID] = t.Number }, "")
+	model.FetchGuests(tx, func(g *model.Guest) {
+		var copy = *g
+		p := byBidder[g.Bidder]
+		if p == nil {
+			p = &paddle{Bidder: g.Bidder}
+			byBidder[g.Bidder] = p
+			paddles = append(paddles, p)
+		}
+		// As with BidderToGuest, the guest who pays for themselves is
+		// the primary holder of the bidder number.
+		if g.PayerID == 0 || len(p.Guests) == 0 {
+			p.Guests = append([]*model.Guest{&copy}, p.Guests...)
+			p.Table = tables[model.FetchParty(tx, g.PartyID).TableID]
+		} else {
+			p.Guests = append(p.Guests, &copy)
+		}
+	}, `bidder!=0 ORDER BY sortname`)
+	if changed != nil {
+		var keep []*paddle
+		for _, p := range paddles {
+			for _, g := range p.Guests {
+				if changed[g.ID] {
+					keep = append(keep, p)
+					break
+				}
+			}
+		}
+		paddles = keep
+	}
+	sort.Slice(paddles, func(i, j int) bool {
+		if paddles[i].Table != paddles[j].Table {
+			return paddles[i].Table < paddles[j].Table
+		}
+		return paddles[i].Bidder < paddles[j].Bidder
+	})
+	return paddles
+}
+
+// servePaddles handles GET /guests/paddles.pdf.  It returns a PDF of bid
+// paddles, one per bidder number, each on its own landscape letter page with
+// the bidder number in huge type, the names and table of the guests using it,
+// and a QR code identifying the payer.  The paddles are in table order.  If
+// the "since" query parameter is given (an RFC 3339 time), only the paddles
+// for guests affected by changes since that time are included.
+func servePaddles(w *request.ResponseWriter, r *request.Request) {
+	var (
+		changed map[db.ID]bool
+		paddles []*paddle
+		pdf     *gofpdf.Fpdf
+		tr      func(string) string
+		buf     bytes.Buffer
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if since := r.FormValue("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		changed = journal.GuestsChangedSince(r.Tx, t)
+	}
+	paddles = fetchPaddles(r.Tx, changed)
+	pdf = gofpdf.New("L", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	for _, p := range paddles {
+		pdf.AddPage()
+		renderPaddle(pdf, tr, p)
+	}
+	if len(paddles) == 0 {
+		pdf.AddPage()
+	}
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="paddles.pdf"`)
+	w.Write(buf.Bytes())
+}
+
+// renderPaddle renders a single bid paddle on the current page.
+func renderPaddle(pdf *gofpdf.Fpdf, tr func(string) string, p *paddle) {
+	const (
+		left  = 36.0
+		width = 720.0
+	)
+	var (
+		number = model.FormatBidder(p.Bidder)
+		size   = 360.0
+		names  []string
+	)
+	// Make the number as big as will fit.
+	pdf.SetFont("helvetica", "B", size)
+	if w := pdf.GetStringWidth(number); w > width {
+		size = size * width / w
+		pdf.SetFontSize(size)
+	}
+	pdf.SetXY(left, 36)
+	pdf.CellFormat(width, 360, number, "", 0, "CM", false, 0, "")
+	for _, g := range p.Guests {
+		names = append(names, g.Name)
+	}
+	pdf.SetFont("helvetica", "B", 32)
+	pdf.SetXY(left, 420)
+	pdf.MultiCell(width, 38, tr(strings.Join(names, " & ")), "", "C", false)
+	if p.Table != 0 {
+		pdf.SetFont("helvetica", "", 24)
+		pdf.SetXY(left, 534)
+		pdf.CellFormat(width, 30, fmt.Sprintf("Table %d", p.Table), "", 0, "CM", false, 0, "")
+	}
+	// The QR code identifies the payer, so that scanning the paddle pulls up
+	// their party.
+	qrcode.Draw(pdf, p.Guests[0].Code(), left+width-66, 510, 66)
+}
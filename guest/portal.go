@@ -0,0 +1,287 @@
+package guest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// portalUser is the username under which changes made through the guest
+// portal are journaled.
+const portalUser = "patron"
+
+// portalView is the response to GET /portal/${token}.
+type portalView struct {
+	Host     db.ID          `json:"host"`
+	Requests string         `json:"requests"` // seating and other requests
+	Guests   []portalGuest  `json:"guests"`
+	Entrees  []portalEntree `json:"entrees"`
+	Cutoff   string         `json:"cutoff"` // RFC 3339, empty if none
+	Closed   bool           `json:"closed"` // past the cutoff
+}
+type portalGuest struct {
+	ID         db.ID  `json:"id"`
+	Name       string `json:"name"` // empty if not yet known
+	Email      string `json:"email"`
+	Entree     string `json:"entree"`
+	Allergies  string `json:"allergies"`
+	GlutenFree bool   `json:"glutenFree"`
+	Vegetarian bool   `json:"vegetarian"`
+}
+type portalEntree struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// portalUpdate is the body of PUT /portal/${token}.  Requests is left alone if
+// it is omitted.
+type portalUpdate struct {
+	Guests   []portalGuest `json:"guests"`
+	Requests *string       `json:"requests"`
+}
+
+// ServePortal handles requests starting with /portal.  These are called by the
+// guest portal on the public Schola web site, through which a host can fill in
+// the names, email addresses, entree choices, and dietary needs of the guests
+// they registered (see portalGuests), and their seating requests.  The host is
+// identified by the token in the link they were sent (see model.PortalLink);
+// no login is needed.
+//
+//	GET /portal/${token}    returns the host's guests (a portalView)
+//	PUT /portal/${token}    updates the guests and requests in the body
+//
+// Changes are refused after the portalCutoff time (RFC 3339) in config.json,
+// or if that isn't set, the galaStart time.  Requests must come from the
+// PortalOrigin.
+func ServePortal(w *request.ResponseWriter, r *request.Request) {
+	var (
+		origin string
+		token  string
+		host   *model.Guest
+	)
+	token, r.URL.Path = request.ShiftPath(r.URL.Path)
+	if token == "" || r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	origin = PortalOrigin()
+	if origin != "*" && r.Header.Get("Origin") != origin {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if host = model.FetchGuestByPortalToken(r.Tx, token); host == nil || host.Cancelled {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		getPortal(w, r, host)
+	case http.MethodPut:
+		savePortal(w, r, host)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// PortalOrigin returns the origin from which guest portal requests are
+// accepted: the portalOrigin in config.json, or if that isn't set, the
+// registerOrigin.
+func PortalOrigin() string {
+	if origin := config.Get("portalOrigin"); origin != "" {
+		return origin
+	}
+	return config.Get("registerOrigin")
+}
+
+// portalCutoff returns the time after which changes through the guest portal
+// are refused, or the zero time if there is no such time.
+func portalCutoff() (cutoff time.Time) {
+	var cstr = config.Get("portalCutoff")
+
+	if cstr == "" {
+		cstr = config.Get("galaStart")
+	}
+	cutoff, _ = time.Parse(time.RFC3339, cstr)
+	return cutoff
+}
+
+// portalGuests returns the guests that the host can see and change through
+// the guest portal: the host, and the guests whose registrations the host paid
+// for, other than cancelled ones, with the host first.  Other members of the
+// host's party are not included, since staff may have put several households'
+// registrations in one party.
+func portalGuests(r *request.Request, host *model.Guest) (guests []*model.Guest) {
+	var (
+		ids  []db.ID
+		seen = map[db.ID]bool{host.ID: true}
+	)
+	model.FetchPurchases(r.Tx, func(p *model.Purchase) {
+		if !seen[p.GuestID] {
+			seen[p.GuestID] = true
+			ids = append(ids, p.GuestID)
+		}
+	}, `item IN (SELECT id FROM item WHERE type=?) AND payer=?`, model.ItemRegistration, host.ID)
+	guests = []*model.Guest{host}
+	for _, id := range ids {
+		if g := model.FetchGuest(r.Tx, id); g != nil && !g.Cancelled {
+			guests = append(guests, g)
+		}
+	}
+	return guests
+}
+
+// getPortal handles GET /portal/${token}.
+func getPortal(w *request.ResponseWriter, r *request.Request, host *model.Guest) {
+	var (
+		view   = portalView{Host: host.ID, Requests: host.Requests}
+		cutoff = portalCutoff()
+	)
+	for _, g := range portalGuests(r, host) {
+		pg := portalGuest{
+			ID: g.ID, Name: g.Name, Email: g.Email, Entree: g.Entree,
+			Allergies: g.Allergies, GlutenFree: g.GlutenFree, Vegetarian: g.Vegetarian,
+		}
+		if g.HasPlaceholderName() {
+			pg.Name = ""
+		}
+		view.Guests = append(view.Guests, pg)
+	}
+	view.Entrees = []portalEntree{}
+	model.FetchEntrees(r.Tx, func(e *model.Entree) {
+		view.Entrees = append(view.Entrees, portalEntree{Code: e.Code, Name: e.Name})
+	}, `active`)
+	if !cutoff.IsZero() {
+		view.Cutoff = cutoff.Format(time.RFC3339)
+		view.Closed = time.Now().After(cutoff)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(&view)
+}
+
+// savePortal handles PUT /portal/${token}.  Only the guests listed in the body
+// are changed, and they must all be among the host's portalGuests.  A guest
+// whose name is given as empty keeps their placeholder name.  Errors that the
+// host can correct are returned as a JSON {"error": message} body with a 400
+// status.  Changed names and email addresses of Stripe customers are sent to
+// Stripe after all of the guests are saved; if any of those updates fails,
+// nothing is changed.
+func savePortal(w *request.ResponseWriter, r *request.Request, host *model.Guest) {
+	var (
+		body    portalUpdate
+		guests  = make(map[db.ID]*model.Guest)
+		updates []*stripeUpdate
+		je      model.JournalEntry
+		changed bool
+	)
+	if cutoff := portalCutoff(); !cutoff.IsZero() && time.Now().After(cutoff) {
+		portalError(w, http.StatusForbidden, "The deadline for changes has passed.  Please call the Schola office at (650) 254–1700.")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, g := range portalGuests(r, host) {
+		guests[g.ID] = g
+	}
+	// Validate everything before changing anything.
+	for _, pg := range body.Guests {
+		guest := guests[pg.ID]
+		if guest == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if pg.Entree = strings.TrimSpace(pg.Entree); !model.ValidEntreeChoice(r.Tx, pg.Entree, guest.Entree) {
+			portalError(w, http.StatusBadRequest, "That entree is not on the menu.")
+			return
+		}
+		if pg.ID == host.ID && strings.TrimSpace(pg.Name) == "" {
+			portalError(w, http.StatusBadRequest, "Please give your name.")
+			return
+		}
+	}
+	for _, pg := range body.Guests {
+		guest := guests[pg.ID]
+		name, email := strings.TrimSpace(pg.Name), strings.TrimSpace(pg.Email)
+		if name == "" {
+			name = guest.Name
+		}
+		// If the guest is a Stripe customer, note the update to make
+		// once everyone is saved.
+		if guest.StripeCustomer != "" && (guest.Name != name || guest.Email != email) {
+			old := *guest
+			updates = append(updates, &stripeUpdate{old: &old, guest: guest})
+		}
+		if name != guest.Name {
+			guest.Sortname = sortname(name)
+		}
+		guest.Name = name
+		guest.Email = email
+		guest.Entree = strings.TrimSpace(pg.Entree)
+		guest.Allergies = strings.TrimSpace(pg.Allergies)
+		guest.GlutenFree = pg.GlutenFree
+		guest.Vegetarian = pg.Vegetarian
+		guest.Save(r.Tx, &je)
+		changed = true
+	}
+	if body.Requests != nil && strings.TrimSpace(*body.Requests) != host.Requests {
+		host.Requests = strings.TrimSpace(*body.Requests)
+		host.Save(r.Tx, &je)
+		changed = true
+	}
+	if status, errmsg := updateStripeCustomers(updates); status != 200 {
+		portalError(w, status, errmsg)
+		return
+	}
+	if changed {
+		r.Username = portalUser
+		journal.Log(r, &je)
+	}
+	w.CommitNoContent(r)
+}
+
+// portalError sends an error response that the guest portal can show to the
+// host.
+func portalError(w *request.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// serveGuestPortalLink handles requests to /guest/${gid}/portal-link, which
+// manage the guest's link to the guest portal.  POST returns the link (as a
+// JSON {"link": link} body), issuing it if the guest doesn't have one yet;
+// it returns 409 if no portalURL is set in config.json.  DELETE revokes the
+// guest's link, so that the next POST issues a different one.
+func serveGuestPortalLink(w *request.ResponseWriter, r *request.Request, guest *model.Guest) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodDelete:
+		model.RevokePortalToken(r.Tx, guest.ID)
+		w.CommitNoContent(r)
+	case http.MethodPost:
+		link := model.PortalLink(r.Tx, guest.ID)
+		if link == "" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if err := r.Tx.Commit(); err != nil {
+			panic(err)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]string{"link": link})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,185 @@
+package guest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/email"
+	"github.com/scholacantorum/gala-backend/letterhead"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// Receipt email status values.
+const (
+	receiptUnsent  = "unsent"  // has email address, never sent
+	receiptSent    = "sent"    // sent, and unchanged since
+	receiptChanged = "changed" // sent, but changed since
+	receiptNoEmail = "noEmail" // no email address; must be printed
+)
+
+// payerReceipt is the year-end receipt for a single payer, along with its
+// email status.
+type payerReceipt struct {
+	Payer  db.ID   `json:"payer"`
+	Name   string  `json:"name"`
+	Email  string  `json:"email"`
+	Status string  `json:"status"`
+	Sent   db.Time `json:"sent"`
+	hash   string
+	guest  *model.Guest
+	data   *receiptData
+}
+
+// fetchPayerReceipts returns the year-end receipts for all payers, sorted by
+// payer name, with their email status.
+func fetchPayerReceipts(tx *sqlx.Tx) (receipts []*payerReceipt) {
+	payers, payerPurchases := fetchPayers(tx)
+	for _, p := range payers {
+		pr := payerReceipt{Payer: p.ID, Name: p.Name, Email: p.Email, guest: p}
+		pr.data = buildReceipt(tx, p, payerPurchases[p.ID])
+		pr.hash = receiptHash(pr.data)
+		if rs := model.FetchReceiptSent(tx, p.ID); rs != nil {
+			pr.Sent = rs.Sent
+			if rs.Hash == pr.hash {
+				pr.Status = receiptSent
+			} else {
+				pr.Status = receiptChanged
+			}
+		} else {
+			pr.Status = receiptUnsent
+		}
+		if p.Email == "" && pr.Status != receiptSent {
+			pr.Status = receiptNoEmail
+		}
+		receipts = append(receipts, &pr)
+	}
+	return receipts
+}
+
+// receiptHash returns a hash of the receipt content, which identifies the
+// version of the receipt.
+func receiptHash(data *receiptData) string {
+	by, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(by)
+	return hex.EncodeToString(sum[:])
+}
+
+// serveReceiptEmail handles requests to /guests/receipts/email.
+func serveReceiptEmail(w *request.ResponseWriter, r *request.Request) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		getReceiptEmailStatus(w, r)
+	case http.MethodPost:
+		sendReceiptEmails(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// getReceiptEmailStatus handles GET /guests/receipts/email.  It returns the
+// email status of each payer's year-end receipt.
+func getReceiptEmailStatus(w *request.ResponseWriter, r *request.Request) {
+	receipts := fetchPayerReceipts(r.Tx)
+	if receipts == nil {
+		receipts = []*payerReceipt{}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(receipts)
+}
+
+// sendReceiptEmails handles POST /guests/receipts/email.  It queues an email
+// to each payer whose year-end receipt has not been sent, or has changed since
+// it was sent.  Payers whose receipts were already sent and haven't changed
+// are skipped, so it is safe to repeat.  Payers without email addresses are
+// skipped; their receipts are in /guests/receipts/print-only.pdf.  It returns
+// the IDs of the payers to whom receipts were sent, and of those whose
+// receipts need to be printed.
+func sendReceiptEmails(w *request.ResponseWriter, r *request.Request) {
+	var result = struct {
+		Sent      []db.ID `json:"sent"`
+		PrintOnly []db.ID `json:"printOnly"`
+	}{[]db.ID{}, []db.ID{}}
+
+	for _, pr := range fetchPayerReceipts(r.Tx) {
+		switch pr.Status {
+		case receiptUnsent, receiptChanged:
+			if !emailReceipt(w, r.Tx, pr) {
+				return
+			}
+			result.Sent = append(result.Sent, pr.Payer)
+		case receiptNoEmail:
+			result.PrintOnly = append(result.PrintOnly, pr.Payer)
+		}
+	}
+	if err := r.Tx.Commit(); err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(&result)
+}
+
+// emailReceipt renders a payer's receipt, queues it for email to the payer, and
+// records that it was sent.  It returns false, having already sent an error
+// response, if the receipt couldn't be generated.
+func emailReceipt(w *request.ResponseWriter, tx *sqlx.Tx, pr *payerReceipt) bool {
+	var buf bytes.Buffer
+
+	pdf, tr := letterhead.New()
+	renderReceiptPDF(pdf, tr, pr.data)
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: receipt for payer %d: %s", pr.Payer, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	message, err := email.YearEndReceiptMessage(tx, pr.guest, buf.Bytes())
+	if err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "yearEndReceipt template: %s", err)
+		return false
+	}
+	e := email.Queue(tx, pr.Payer, "yearEndReceipt", message)
+	(&model.ReceiptSent{PayerID: pr.Payer, Hash: pr.hash, EmailID: e.ID, Sent: db.Time{Time: time.Now()}}).Save(tx)
+	return true
+}
+
+// servePrintOnlyReceipts handles GET /guests/receipts/print-only.pdf.  It
+// returns a PDF of the receipts of those payers who can't be sent their
+// receipts by email, for printing and mailing.
+func servePrintOnlyReceipts(w *request.ResponseWriter, r *request.Request) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	pdf, tr := letterhead.New()
+	for _, pr := range fetchPayerReceipts(r.Tx) {
+		if pr.Status == receiptNoEmail {
+			renderReceiptPDF(pdf, tr, pr.data)
+		}
+	}
+	if pdf.PageCount() == 0 {
+		pdf.AddPage()
+	}
+	sendPDF(w, pdf, "receipts-print-only.pdf")
+}
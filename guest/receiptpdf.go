@@ -0,0 +1,223 @@
+package guest
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/letterhead"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// serveReceiptsPDF handles GET /guests/receipts.pdf.  It returns the same
+// receipts as serveReceipts, as a single PDF with one receipt per page.
+func serveReceiptsPDF(w *request.ResponseWriter, r *request.Request) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	pdf, tr := letterhead.New()
+	payers, payerPurchases := fetchPayers(r.Tx)
+	for _, p := range payers {
+		renderReceiptPDF(pdf, tr, buildReceipt(r.Tx, p, payerPurchases[p.ID]))
+	}
+	if len(payers) == 0 {
+		pdf.AddPage()
+	}
+	sendPDF(w, pdf, "receipts.pdf")
+}
+
+// serveGuestReceiptPDF handles GET /guest/${gid}/receipt.pdf.  It returns the
+// receipt for the specified payer as a PDF.
+func serveGuestReceiptPDF(w *request.ResponseWriter, r *request.Request, guest *model.Guest) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	purchases := fetchPayerPurchases(r.Tx, guest.ID)
+	if len(purchases) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	pdf, tr := letterhead.New()
+	renderReceiptPDF(pdf, tr, buildReceipt(r.Tx, guest, purchases))
+	sendPDF(w, pdf, fmt.Sprintf("receipt-%d.pdf", guest.ID))
+}
+
+// sendPDF renders the PDF and sends it as the response.  It is rendered into
+// a buffer first so that a rendering error can still produce a 500 response.
+func sendPDF(w *request.ResponseWriter, pdf *gofpdf.Fpdf, filename string) {
+	var buf bytes.Buffer
+
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Write(buf.Bytes())
+}
+
+// Column widths for the receipt tables.  The item column gets whatever is left
+// over.
+const (
+	receiptValueWidth  = 72.0
+	receiptAmountWidth = 60.0
+	receiptDateWidth   = 72.0
+	receiptMethodWidth = 132.0
+	receiptNoteWidth   = 8.0
+	receiptLineHeight  = 14.0
+)
+
+// renderReceiptPDF adds a page (or more, if needed) to the PDF containing the
+// receipt.  The content is the same as payerTemplate's.
+func renderReceiptPDF(pdf *gofpdf.Fpdf, tr func(string) string, data *receiptData) {
+	pdf.AddPage()
+	pdf.SetFont("helvetica", "", 11)
+	if len(data.Purchases) != 0 {
+		receiptIntro(pdf, tr, "Schola Cantorum confirms the following "+data.PurchaseTypes+" from ", data.Payer, ":")
+		widths := []float64{0, receiptValueWidth, receiptAmountWidth, receiptDateWidth, receiptMethodWidth}
+		aligns := []string{"L", "R", "R", "L", "L"}
+		widths[0] = letterhead.Width - widths[1] - widths[2] - widths[3] - widths[4]
+		receiptTableHeading(pdf, widths, aligns, []string{"Item", "Estimated\nValue\nReceived", "Amount\nPaid", "Payment\nDate", "Payment\nMethod"})
+		for _, p := range data.Purchases {
+			receiptTableRow(pdf, tr, widths, p, true)
+		}
+		if data.TotalAmount != 0 {
+			pdf.SetFont("helvetica", "B", 11)
+			if data.ShowTotalValue {
+				pdf.CellFormat(widths[0], receiptLineHeight, "TOTAL", "", 0, "R", false, 0, "")
+				pdf.CellFormat(widths[1]-receiptNoteWidth, receiptLineHeight, fmt.Sprintf("$%d", data.TotalValue), "T", 0, "R", false, 0, "")
+				pdf.CellFormat(receiptNoteWidth, receiptLineHeight, "", "T", 0, "", false, 0, "")
+			} else {
+				pdf.CellFormat(widths[0]+widths[1], receiptLineHeight, "TOTAL", "", 0, "R", false, 0, "")
+			}
+			pdf.CellFormat(widths[2], receiptLineHeight, fmt.Sprintf("$%d", data.TotalAmount), "T", 1, "R", false, 0, "")
+			pdf.SetFont("helvetica", "", 11)
+		}
+		pdf.Ln(12)
+	}
+	if len(data.ThirdParty) != 0 {
+		receiptIntro(pdf, tr, "Schola Cantorum thanks ", data.Payer, " for arranging the following third-party "+data.ThirdPartyTypes+":")
+		widths := []float64{0, receiptAmountWidth, receiptDateWidth, receiptMethodWidth}
+		aligns := []string{"L", "R", "L", "L"}
+		widths[0] = letterhead.Width - widths[1] - widths[2] - widths[3]
+		receiptTableHeading(pdf, widths, aligns, []string{"Item", "Amount\nPaid", "Payment\nDate", "Payment\nMethod"})
+		for _, p := range data.ThirdParty {
+			receiptTableRow(pdf, tr, widths, p, false)
+		}
+		pdf.Ln(12)
+	}
+	if data.ShowRegistrationNote {
+		receiptNote(pdf, tr, "*", `The "estimated value received" for registration is our good faith estimate.  It may not reflect the fair market value.`)
+	}
+	if data.ShowPurchaseNote {
+		receiptNote(pdf, tr, "†", `The "estimated value received" for auction items is provided by the donor.  It may not reflect the fair market value.`)
+	}
+	if data.ShowDonationNote {
+		receiptNote(pdf, tr, "§", "No goods or services were received in return for this donation.")
+	}
+	pdf.Ln(12)
+	pdf.MultiCell(letterhead.Width, receiptLineHeight, "Schola Cantorum is a 501(c)(3) tax-exempt organization.  Our federal tax ID number is 94-2597822.", "", "L", false)
+	pdf.Ln(12)
+	pdf.MultiCell(letterhead.Width, receiptLineHeight, "Thank you for your support of Schola Cantorum!", "", "L", false)
+}
+
+// receiptIntro renders the paragraph introducing a receipt table, with the
+// payer's name in bold.
+func receiptIntro(pdf *gofpdf.Fpdf, tr func(string) string, before, payer, after string) {
+	pdf.Write(receiptLineHeight, tr(before))
+	pdf.SetFont("helvetica", "B", 11)
+	pdf.Write(receiptLineHeight, tr(payer))
+	pdf.SetFont("helvetica", "", 11)
+	pdf.Write(receiptLineHeight, tr(after))
+	pdf.Ln(receiptLineHeight + 8)
+}
+
+// receiptTableHeading renders the (possibly multi-line) column headings of a
+// receipt table, aligned to their bottoms.
+func receiptTableHeading(pdf *gofpdf.Fpdf, widths []float64, aligns []string, headings []string) {
+	var (
+		lines    = make([][]string, len(headings))
+		maxLines int
+	)
+	for i, h := range headings {
+		lines[i] = strings.Split(h, "\n")
+		maxLines = max(maxLines, len(lines[i]))
+	}
+	pdf.SetFont("helvetica", "B", 11)
+	y := pdf.GetY()
+	x := letterhead.Left
+	for i := range headings {
+		pdf.SetXY(x, y+float64(maxLines-len(lines[i]))*receiptLineHeight)
+		for _, line := range lines[i] {
+			if i != 0 && aligns[i] == "L" {
+				line = "  " + line // match the padding on the data cells
+			}
+			pdf.CellFormat(widths[i], receiptLineHeight, line, "", 2, aligns[i], false, 0, "")
+		}
+		x += widths[i]
+	}
+	pdf.SetXY(letterhead.Left, y+float64(maxLines)*receiptLineHeight)
+	pdf.SetFont("helvetica", "", 11)
+}
+
+// receiptTableRow renders a single line item of a receipt table.  If
+// showValue is true, the row includes the estimated value column.  Long item
+// names wrap within their column.
+func receiptTableRow(pdf *gofpdf.Fpdf, tr func(string) string, widths []float64, p *receiptPurchase, showValue bool) {
+	items := pdf.SplitLines([]byte(tr(p.Item)), widths[0]-4)
+	height := float64(len(items)) * receiptLineHeight
+	if pdf.GetY()+height > letterhead.Bottom {
+		pdf.AddPage()
+		pdf.SetFont("helvetica", "", 11)
+	}
+	y := pdf.GetY()
+	for _, line := range items {
+		pdf.CellFormat(widths[0], receiptLineHeight, string(line), "", 2, "L", false, 0, "")
+	}
+	pdf.SetXY(letterhead.Left+widths[0], y)
+	widths = widths[1:]
+	if showValue {
+		pdf.CellFormat(widths[0]-receiptNoteWidth, receiptLineHeight, fmt.Sprintf("$%d", p.Value), "", 0, "R", false, 0, "")
+		pdf.SetFont("helvetica", "", 7)
+		pdf.CellFormat(receiptNoteWidth, receiptLineHeight/2, tr(p.Note), "", 0, "L", false, 0, "")
+		pdf.SetFont("helvetica", "", 11)
+		widths = widths[1:]
+	}
+	if p.Amount != 0 {
+		pdf.CellFormat(widths[0], receiptLineHeight, fmt.Sprintf("$%d", p.Amount), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[1], receiptLineHeight, "  "+p.Date, "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], receiptLineHeight, "  "+tr(p.Method), "", 0, "L", false, 0, "")
+	} else {
+		pdf.SetFont("helvetica", "I", 11)
+		pdf.CellFormat(widths[0]+widths[1]+widths[2], receiptLineHeight, "  not yet paid", "", 0, "L", false, 0, "")
+		pdf.SetFont("helvetica", "", 11)
+	}
+	pdf.SetXY(letterhead.Left, y+height)
+}
+
+// receiptNote renders one of the footnotes explaining the marks on the
+// estimated values.
+func receiptNote(pdf *gofpdf.Fpdf, tr func(string) string, mark, text string) {
+	y := pdf.GetY()
+	pdf.SetFont("helvetica", "", 7)
+	pdf.CellFormat(receiptNoteWidth, receiptLineHeight/2, tr(mark), "", 0, "L", false, 0, "")
+	pdf.SetFont("helvetica", "", 11)
+	pdf.SetXY(letterhead.Left+receiptNoteWidth, y)
+	pdf.MultiCell(letterhead.Width-receiptNoteWidth, receiptLineHeight, tr(text), "", "L", false)
+}
@@ -6,16 +6,33 @@ import (
 	"net/http"
 	"sort"
 
+	"github.com/jmoiron/sqlx"
+
 	"github.com/scholacantorum/gala-backend/db"
 	"github.com/scholacantorum/gala-backend/model"
 	"github.com/scholacantorum/gala-backend/request"
 )
 
+// serveReceipts handles requests starting with /guests/receipts.
 func serveReceipts(w *request.ResponseWriter, r *request.Request) {
-	if r.URL.Path != "/" {
+	var head string
+
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	switch head {
+	case "":
+		serveReceiptsHTML(w, r)
+	case "email":
+		serveReceiptEmail(w, r)
+	case "print-only.pdf":
+		servePrintOnlyReceipts(w, r)
+	default:
 		w.WriteHeader(http.StatusNotFound)
-		return
 	}
+}
+
+// serveReceiptsHTML handles GET /guests/receipts.  It returns an HTML page
+// with the year-end receipts for all payers, for printing from a browser.
+func serveReceiptsHTML(w *request.ResponseWriter, r *request.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
@@ -71,55 +88,74 @@ func serveReceipts(w *request.ResponseWriter, r *request.Request) {
 Info@ScholaCantorum.org • Schola Cantorum is a 501(c)(3) nonprofit organization, tax ID 94-2597822
 </div>
 `)
-	var payers []*model.Guest
-	payerPurchases := map[db.ID][]*model.Purchase{}
-	model.FetchGuests(r.Tx, func(g *model.Guest) {
-		var purchases []*model.Purchase
-		model.FetchPurchases(r.Tx, func(p *model.Purchase) {
-			if !p.Unbid {
-				copy := *p
-				purchases = append(purchases, &copy)
-			}
-		}, `payer=?`, g.ID)
-		if len(purchases) != 0 {
+	payers, payerPurchases := fetchPayers(r.Tx)
+	for _, p := range payers {
+		payerTemplate.Execute(w, buildReceipt(r.Tx, p, payerPurchases[p.ID]))
+	}
+	fmt.Fprint(w, `</body></html>
+`)
+	w.Close()
+}
+
+// fetchPayers returns all guests who are payers of any (bid) purchases, sorted
+// by name, along with those purchases.
+func fetchPayers(tx *sqlx.Tx) (payers []*model.Guest, payerPurchases map[db.ID][]*model.Purchase) {
+	payerPurchases = make(map[db.ID][]*model.Purchase)
+	model.FetchGuests(tx, func(g *model.Guest) {
+		if purchases := fetchPayerPurchases(tx, g.ID); len(purchases) != 0 {
 			copy := *g
 			payerPurchases[g.ID] = purchases
 			payers = append(payers, &copy)
 		}
 	}, "1 ORDER BY sortname")
 	sort.Slice(payers, func(i, j int) bool { return payers[i].Sortname < payers[j].Sortname })
-	for _, p := range payers {
-		emitReceipt(w, r, p, payerPurchases[p.ID])
-	}
-	fmt.Fprint(w, `</body></html>
-`)
-	w.Close()
+	return payers, payerPurchases
 }
 
-func emitReceipt(w *request.ResponseWriter, r *request.Request, payer *model.Guest, purchases []*model.Purchase) {
-	type purchase struct {
-		ItemID   db.ID
-		Item     string
-		Amount   int
-		Value    int
-		Note     string
-		Date     string
-		Method   string
-		Quantity int
-	}
-	var receiptData struct {
-		Payer                string
-		PurchaseTypes        string
-		ShowTotalValue       bool
-		TotalValue           int
-		TotalAmount          int
-		Purchases            []*purchase
-		ShowRegistrationNote bool
-		ShowPurchaseNote     bool
-		ShowDonationNote     bool
-		ThirdPartyTypes      string
-		ThirdParty           []*purchase
-	}
+// fetchPayerPurchases returns the (bid) purchases paid for by the specified
+// payer.
+func fetchPayerPurchases(tx *sqlx.Tx, payer db.ID) (purchases []*model.Purchase) {
+	model.FetchPurchases(tx, func(p *model.Purchase) {
+		if !p.Unbid {
+			copy := *p
+			purchases = append(purchases, &copy)
+		}
+	}, `payer=?`, payer)
+	return purchases
+}
+
+// receiptPurchase is a line item on a receipt.
+type receiptPurchase struct {
+	ItemID   db.ID
+	Item     string
+	Amount   int
+	Value    int
+	Note     string
+	Date     string
+	Method   string
+	Quantity int
+}
+
+// receiptData is the content of a receipt for a single payer.  It is rendered
+// either by payerTemplate (HTML) or by renderReceiptPDF.
+type receiptData struct {
+	Payer                string
+	PurchaseTypes        string
+	ShowTotalValue       bool
+	TotalValue           int
+	TotalAmount          int
+	Purchases            []*receiptPurchase
+	ShowRegistrationNote bool
+	ShowPurchaseNote     bool
+	ShowDonationNote     bool
+	ThirdPartyTypes      string
+	ThirdParty           []*receiptPurchase
+}
+
+// buildReceipt assembles the receipt content for the specified payer and their
+// purchases.
+func buildReceipt(tx *sqlx.Tx, payer *model.Guest, purchases []*model.Purchase) *receiptData {
+	var receiptData receiptData
 	var (
 		purchasesCount      int
 		donations           int
@@ -135,20 +171,20 @@ func emitReceipt(w *request.ResponseWriter, r *request.Request, payer *model.Gue
 	receiptData.Payer = payer.Name
 	receiptData.ShowTotalValue = true
 	for _, p := range purchases {
-		item := model.FetchItem(r.Tx, p.ItemID)
-		purchase := purchase{
+		item := model.FetchItem(tx, p.ItemID)
+		purchase := receiptPurchase{
 			ItemID:   p.ItemID,
 			Item:     item.Name,
 			Amount:   p.Amount / 100,
 			Value:    item.Value / 100,
 			Quantity: 1,
 		}
-		if item.ID == 1 { // registration
+		if item.Type == model.ItemRegistration {
 			purchase.Note = "*"
 			if !p.ThirdParty {
 				receiptData.ShowRegistrationNote = true
 			}
-		} else if item.Value != 0 {
+		} else if !item.IsDonation() {
 			purchase.Note = "†"
 			if !p.ThirdParty {
 				receiptData.ShowPurchaseNote = true
@@ -166,7 +202,7 @@ func emitReceipt(w *request.ResponseWriter, r *request.Request, payer *model.Gue
 			} else {
 				purchase.Date = p.PaymentTimestamp[0:10]
 				purchase.Method = p.PaymentDescription
-				if item.Value != 0 {
+				if !item.IsDonation() {
 					thirdPartyCount++
 				} else {
 					thirdPartyDonations++
@@ -183,7 +219,7 @@ func emitReceipt(w *request.ResponseWriter, r *request.Request, payer *model.Gue
 			} else {
 				purchase.Date = p.PaymentTimestamp[0:10]
 				purchase.Method = p.PaymentDescription
-				if item.Value != 0 {
+				if !item.IsDonation() {
 					purchasesCount++
 				} else {
 					donations++
@@ -282,8 +318,7 @@ func emitReceipt(w *request.ResponseWriter, r *request.Request, payer *model.Gue
 		receiptData.TotalAmount = 0
 	}
 
-	// Render the email template.
-	payerTemplate.Execute(w, &receiptData)
+	return &receiptData
 }
 
 var payerTemplate = template.Must(template.New("payer").Parse(`
@@ -1,24 +1,19 @@
 package guest
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"html"
-	"io"
 	"log"
 	"net/http"
-	"net/mail"
 	"strconv"
 	"strings"
-	"text/tabwriter"
 	"time"
 
 	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/email"
 	"github.com/scholacantorum/gala-backend/journal"
 	"github.com/scholacantorum/gala-backend/model"
 	"github.com/scholacantorum/gala-backend/request"
-	"github.com/scholacantorum/gala-backend/sendmail"
 )
 
 type orderInfo struct {
@@ -41,6 +36,7 @@ func ServeRegister(w *request.ResponseWriter, r *request.Request) {
 		message string
 		guests  []*model.Guest
 		je      model.JournalEntry
+		regItem *model.Item
 		missing bool
 	)
 	if head, _ := request.ShiftPath(r.URL.Path); head != "" {
@@ -57,6 +53,18 @@ func ServeRegister(w *request.ResponseWriter, r *request.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
+	// Make sure the entree choices are valid before charging anything.
+	if message = checkRegisterEntrees(r); message != "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+	if regItem = model.FetchRegistrationItem(r.Tx); regItem == nil {
+		log.Print("Registration refused: there is no registration item")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]string{"error": "Registration is not available.  Please call the Schola office at (650) 254–1700."})
+		return
+	}
 	// Charge the order in Schola's ordering system.
 	if oinfo, message = chargePublicRegister(r); message != "" {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -68,19 +76,36 @@ func ServeRegister(w *request.ResponseWriter, r *request.Request) {
 		return
 	}
 	// Save the registration(s) in our database.
-	guests, missing = publicRegister(r, oinfo, &je)
+	guests, missing = publicRegister(r, oinfo, regItem, &je)
 	journal.Log(r, &je)
+	// Queue the registration confirmation email.
+	publicRegisterReceipt(r, oinfo, guests, missing)
 	if err := r.Tx.Commit(); err != nil {
 		panic(err)
 	}
-	// Send the registration confirmation email.
-	publicRegisterReceipt(oinfo, guests, missing)
 	// The registration form is expecting to get an ID back; that's its
 	// indication of success.
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	fmt.Fprintf(w, `{"id":%d}`, oinfo.id)
 }
 
+// checkRegisterEntrees verifies that each entree choice on the registration
+// form is either empty or an active entree.  It returns an error message for
+// the registrant if not.
+func checkRegisterEntrees(r *request.Request) string {
+	for i := 1; true; i++ {
+		prefix := fmt.Sprintf("line%d.", i)
+		if i > 1 && r.FormValue(prefix+"product") == "" {
+			break
+		}
+		code := strings.TrimSpace(r.FormValue(prefix + "option"))
+		if !model.ValidEntreeChoice(r.Tx, code, "") {
+			return fmt.Sprintf("%q is not one of the entrees on the menu.", code)
+		}
+	}
+	return ""
+}
+
 func chargePublicRegister(r *request.Request) (*orderInfo, string) {
 	type responsedata struct {
 		Error    string `json:"error"`
@@ -133,7 +158,9 @@ func chargePublicRegister(r *request.Request) (*orderInfo, string) {
 	}, ""
 }
 
-func publicRegister(r *request.Request, oinfo *orderInfo, je *model.JournalEntry) (guests []*model.Guest, missing bool) {
+func publicRegister(
+	r *request.Request, oinfo *orderInfo, regItem *model.Item, je *model.JournalEntry,
+) (guests []*model.Guest, missing bool) {
 	var (
 		host     model.Guest
 		guest    *model.Guest
@@ -142,7 +169,7 @@ func publicRegister(r *request.Request, oinfo *orderInfo, je *model.JournalEntry
 
 	// Set up the purchases.
 	purchase = model.Purchase{
-		ItemID:             1,
+		ItemID:             regItem.ID,
 		PaymentDescription: oinfo.card,
 		ScholaOrder:        oinfo.id,
 		PaymentTimestamp:   time.Now().Format(time.RFC3339),
@@ -164,6 +191,7 @@ func publicRegister(r *request.Request, oinfo *orderInfo, je *model.JournalEntry
 	if host.Entree == "" {
 		missing = true
 	}
+	registerNeeds(r, "line1.", &host)
 	host.Sortname = sortname(host.Name)
 	host.Requests = strings.TrimSpace(r.FormValue("cNote"))
 	host.StripeCustomer = oinfo.customer
@@ -198,6 +226,7 @@ func publicRegister(r *request.Request, oinfo *orderInfo, je *model.JournalEntry
 		if guest.Entree == "" {
 			missing = true
 		}
+		registerNeeds(r, prefix, guest)
 		guest.ID = 0 // force new creation
 		guest.Save(r.Tx, je)
 		guests = append(guests, guest)
@@ -209,6 +238,27 @@ func publicRegister(r *request.Request, oinfo *orderInfo, je *model.JournalEntry
 	return guests, missing
 }
 
+// registerNeeds sets the guest's dietary restrictions and accessibility needs
+// from the registration form fields with the specified prefix.
+func registerNeeds(r *request.Request, prefix string, guest *model.Guest) {
+	guest.Allergies = strings.TrimSpace(r.FormValue(prefix + "allergies"))
+	guest.GlutenFree = formFlag(r, prefix+"glutenFree")
+	guest.Vegetarian = formFlag(r, prefix+"vegetarian")
+	guest.Wheelchair = formFlag(r, prefix+"wheelchair")
+	guest.HearingAssist = formFlag(r, prefix+"hearingAssist")
+}
+
+// formFlag returns whether the named checkbox on the registration form is
+// checked.
+func formFlag(r *request.Request, name string) bool {
+	switch strings.ToLower(r.FormValue(name)) {
+	case "", "0", "false", "no", "off":
+		return false
+	default:
+		return true
+	}
+}
+
 var knownSuffixes = []string{" jr", " jr.", " sr", " sr.", " iii", " md", " m.d."}
 
 func sortname(name string) string {
@@ -232,103 +282,19 @@ func sortname(name string) string {
 	return name + suffix
 }
 
-func publicRegisterReceipt(oinfo *orderInfo, guests []*model.Guest, missing bool) {
-	var (
-		message sendmail.Message
-		addr    mail.Address
-		tb      bytes.Buffer
-		hb      bytes.Buffer
-		tw      *tabwriter.Writer
-	)
-	message.From = "Schola Cantorum <admin@scholacantorum.org>"
-	addr.Name = oinfo.name
-	addr.Address = oinfo.email
-	message.To = []string{addr.String()}
-	message.Bcc = []string{"admin@scholacantorum.org"}
-	if bcc := config.Get("receiptBCC"); bcc != "" {
-		message.Bcc = append(message.Bcc, strings.Split(bcc, ",")...)
-	}
-	message.Subject = fmt.Sprintf("Schola Cantorum Order #%d", oinfo.id)
-	message.Images = [][]byte{sendmail.ScholaLogoPNG}
-
-	io.WriteString(&tb, `Dear Fabulous Schola Supporter,
-
-We are overjoyed that you will be joining us for our annual party and
-fundraiser, “Rhythms of Rio”, on Saturday, April 26, 2025, at Saratoga
-Country Club, 21990 Prospect Road, Saratoga.  The festivities commence at 6:00pm
-and will continue until 10:00pm.
-
-`)
-	io.WriteString(&hb, `<!DOCTYPE html><html><head><style>p{margin:0}p+p,table+p,pre+p{margin-top:1em}table{border-collapse:collapse;margin-top:0.75em}td,th{text-align:left;padding:0.25em 1em 0 0;line-height:1}th{font-weight:normal;text-decoration:underline}pre{margin:0}</style><body style="margin:0"><div style="width:600px;margin:0 auto"><div style="margin-bottom:24px"><img src="cid:IMG0" alt="[Schola Cantorum]" style="border-width:0"></div><p>Dear Fabulous Schola Supporter,</p><p>We are overjoyed that you will be joining us for our annual party and fundraiser, “Rhythms of Rio”, on Saturday, April 26, 2025, at Saratoga Country Club, 21990 Prospect Road, Saratoga (see <a href="https://www.google.com/maps/place/Saratoga+Country+Club/@37.284146,-122.0706404,14z/data=!4m6!3m5!1s0x808fb4c4b0258435:0x39980b6fabeaf7de!8m2!3d37.284146!4d-122.052616!16s%2Fg%2F1tgx6vjd?entry=ttu">map</a>).  The festivities commence at 6:00pm and will continue until 10:00pm.</p>`)
-	switch len(guests) {
-	case 1:
-		io.WriteString(&tb, "You have purchased one ticket for $215:\n\n")
-		io.WriteString(&hb, "<p>You have purchased one ticket for $215:</p>")
-	case 10:
-		io.WriteString(&tb, "You have purchased a table for the following 10 guests at $215 per person:\n\n")
-		io.WriteString(&hb, "<p>You have purchased a table for the following 10 guests at $215 per person:</p>")
-	default:
-		fmt.Fprintf(&tb, "You have purchased %d tickets for the following guests at $215 per person:\n\n", len(guests))
-		fmt.Fprintf(&hb, "<p>You have purchased %d tickets for the following guests at $215 per person:</p>", len(guests))
-	}
-	tw = tabwriter.NewWriter(&tb, 0, 0, 2, ' ', 0)
-	io.WriteString(tw, "\tGuest Name\tEntrée\n")
-	io.WriteString(&hb, "<table><tr><th><th>Guest Name<th>Entrée</tr>")
-	for i, g := range guests {
-		entree := entreeName(g.Entree)
-		fmt.Fprintf(tw, "%d.\t%s\t%s\n", i+1, g.Name, entree)
-		fmt.Fprintf(&hb, "<tr><td>%d.<td>%s<td>%s</tr>", i+1, html.EscapeString(g.Name), html.EscapeString(entree))
+func publicRegisterReceipt(r *request.Request, oinfo *orderInfo, guests []*model.Guest, missing bool) {
+	order := email.RegistrationOrder{
+		ID:    oinfo.id,
+		Name:  oinfo.name,
+		Email: oinfo.email,
+		Card:  oinfo.card,
+		Total: oinfo.total,
+		Date:  time.Now(),
 	}
-	tw.Flush()
-	io.WriteString(&tb, "\n")
-	io.WriteString(&hb, "</table>")
-	if guests[0].Requests != "" {
-		fmt.Fprintf(&tb, "Special Requests:\n%s\n\n", guests[0].Requests)
-		fmt.Fprintf(&hb, "<p><u>Special Requests</u></p><pre>%s</pre>", html.EscapeString(guests[0].Requests))
-	}
-	if missing {
-		io.WriteString(&tb, `We need all guest names and entree choices no later than April 12.  We would
-also like to know of any dietary restrictions or seating requests.  To supply
-those, or to correct any errors, please reply to this email.  You can also call
-the Schola office at (650) 254–1700.
-
-`)
-		io.WriteString(&hb, `<p>We need all guest names and entree choices no later than April 12.  We would also like to know of any dietary restrictions or seating requests.  To supply those, or to correct any errors, please reply to this email.  You can also call the Schola office at (650)&nbsp;254–1700.</p>`)
-	} else {
-		io.WriteString(&tb, `If you need to make any corrections, or add any dietary restrictions or seating
-requests, please do so by April 12.  You can reply to this email, or call the
-Schola Office at (650) 254–1700.
-
-`)
-		io.WriteString(&hb, `<p>If you need to make any corrections, or add any dietary restrictions or seating requests, please do so by April 12.  You can reply to this email, or call the Schola Office at (650)&nbsp;254–1700.</p>`)
-	}
-	fmt.Fprintf(&tb, "For your records, you paid a total of $%d on %s by %s.\n\n", oinfo.total/100, time.Now().Format("January 2, 2006"), oinfo.card)
-	fmt.Fprintf(&hb, `<p>For your records, you paid a total of $%d on %s by %s.</p>`, oinfo.total/100, time.Now().Format("January 2, 2006"), oinfo.card)
-	io.WriteString(&tb, `Reservations will be held at the door; no tickets will be mailed to you.  When
-you arrive, please check in at the registration table, get your program, and
-provide your credit card number for purchases made at the event. There will
-be complimentary champagne, wine, and soft drinks for all guests.
-
-`)
-	io.WriteString(&hb, `<p>Reservations will be held at the door; no tickets will be mailed to you.  When you arrive, please check in at the registration table, get your program, and provide your credit card number for purchases made at the event.  There will be complimentary champagne, wine, and soft drinks for all guests.</p>`)
-	io.WriteString(&tb, "Musically yours,\nSchola Cantorum Silicon Valley\n\nWeb: scholacantorum.org\nEmail: info@scholacantorum.org\nPhone: (650) 254–1700\n")
-	io.WriteString(&hb, `<p>Musically yours,<br>Schola Cantorum Silicon Valley<p>Web: <a href="https://scholacantorum.org">scholacantorum.org</a><br>Email: <a href="mailto:info@scholacantorum.org">info@scholacantorum.org</a><br>Phone: <a href="tel:16502541700">(650) 254–1700</a></p></div></body></html>`)
-	message.Text = tb.String()
-	message.HTML = hb.String()
-	message.Send()
-}
-
-func entreeName(code string) string {
-	switch code {
-	case "":
-		return "(not yet selected)"
-	case "filet":
-		return "Filet Mignon"
-	case "salmon":
-		return "Salmon"
-	case "vegan":
-		return "Vegan"
-	default:
-		return code
+	message, err := email.RegistrationMessage(r.Tx, &order, guests, missing)
+	if err != nil {
+		log.Printf("ERROR: registration email for order %d: %s", oinfo.id, err)
+		return
 	}
+	email.Queue(r.Tx, guests[0].ID, "registration", message)
 }
@@ -0,0 +1,194 @@
+package guest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/email"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// reminderInterval is how often the reminder scheduler checks for reminders
+// to send.
+const reminderInterval = time.Hour
+
+// reminderHost is a host whose guests' names or entree choices are still
+// missing, along with the reminders they have been sent.
+type reminderHost struct {
+	Host       db.ID           `json:"host"`
+	Name       string          `json:"name"`
+	Email      string          `json:"email"`
+	Phone      string          `json:"phone"`
+	Guests     []reminderGuest `json:"guests"`
+	Reminders  int             `json:"reminders"` // number sent
+	LastSent   db.Time         `json:"lastSent"`  // zero if none sent
+	registered time.Time       // time of the host's first registration
+	host       *model.Guest
+	guests     []*model.Guest
+}
+type reminderGuest struct {
+	Guest   db.ID    `json:"guest"`
+	Name    string   `json:"name"`    // empty if not yet known
+	Missing []string `json:"missing"` // "name", "entree choice"
+}
+
+// fetchReminderHosts returns the hosts whose guests' names or entree choices
+// are still missing, sorted by host name.  A host is anyone who paid for a
+// registration; their guests are those whose registrations they paid for.
+// Cancelled hosts are omitted.
+func fetchReminderHosts(tx *sqlx.Tx) (hosts []*reminderHost) {
+	var registered = make(map[db.ID]time.Time)
+
+	model.FetchPurchases(tx, func(p *model.Purchase) {
+		ts, _ := time.Parse(time.RFC3339, p.PaymentTimestamp)
+		if first, ok := registered[p.PayerID]; !ok || ts.Before(first) {
+			registered[p.PayerID] = ts
+		}
+	}, `item IN (SELECT id FROM item WHERE type=?)`, model.ItemRegistration)
+	for hid, ts := range registered {
+		host := model.FetchGuest(tx, hid)
+		if host == nil || host.Cancelled {
+			continue
+		}
+		guests := email.ReminderGuests(tx, host)
+		if len(guests) == 0 {
+			continue
+		}
+		rh := reminderHost{
+			Host: host.ID, Name: host.Name, Email: host.Email, Phone: host.Phone,
+			registered: ts, host: host, guests: guests,
+		}
+		for _, g := range guests {
+			rg := reminderGuest{Guest: g.ID, Name: g.Name, Missing: g.MissingInfo()}
+			if g.HasPlaceholderName() {
+				rg.Name = ""
+			}
+			rh.Guests = append(rh.Guests, rg)
+		}
+		if rs := model.FetchReminderSent(tx, host.ID); rs != nil {
+			rh.Reminders = rs.Count
+			rh.LastSent = rs.Sent
+		}
+		hosts = append(hosts, &rh)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].host.Sortname < hosts[j].host.Sortname })
+	return hosts
+}
+
+// serveReminders handles GET /guests/reminders.  It returns the hosts whose
+// guests' names or entree choices are still missing, with what's missing and
+// how many reminders they've been sent, for phone follow-up.
+func serveReminders(w *request.ResponseWriter, r *request.Request) {
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	hosts := fetchReminderHosts(r.Tx)
+	if hosts == nil {
+		hosts = []*reminderHost{}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(hosts)
+}
+
+// Reminders is a goroutine that emails reminders to hosts whose guests' names
+// or entree choices are still missing.  Reminders are sent the numbers of days
+// before the infoDeadline (see email.InfoDeadline) given by the reminderDays
+// setting in config.json, e.g. "14,7,2".  Each host gets at most one reminder
+// on each of those days, and at most reminderLimit in all (by default, as many
+// as there are reminderDays).  Hosts who registered after a reminder day
+// aren't reminded on it, since their registration confirmation already told
+// them what was missing.  The reminders are sent while holding lock, which
+// must be the lock that serializes request handling, so that requests wait
+// for the reminders rather than failing on a busy database.
+func Reminders(dbh *sqlx.DB, lock sync.Locker) {
+	for {
+		lock.Lock()
+		sendDueReminders(dbh, time.Now())
+		lock.Unlock()
+		time.Sleep(reminderInterval)
+	}
+}
+
+// reminderSchedule returns the most recent reminder time at or before now,
+// and the maximum number of reminders per host.  It returns the zero time if
+// no reminders are due: none are configured, none have come yet, or the
+// deadline has passed.
+func reminderSchedule(now time.Time) (due time.Time, limit int) {
+	var deadline = email.InfoDeadline()
+
+	if deadline.IsZero() || !now.Before(deadline) {
+		return time.Time{}, 0
+	}
+	for _, dstr := range strings.Split(config.Get("reminderDays"), ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(dstr))
+		if err != nil || days < 1 {
+			continue
+		}
+		limit++
+		if t := deadline.AddDate(0, 0, -days); !t.After(now) && t.After(due) {
+			due = t
+		}
+	}
+	if l, err := strconv.Atoi(config.Get("reminderLimit")); err == nil && l >= 0 {
+		limit = l
+	}
+	return due, limit
+}
+
+// sendDueReminders queues a reminder email to each host who is due for one.
+// It catches any panic, so that a database error doesn't take down the
+// server.
+func sendDueReminders(dbh *sqlx.DB, now time.Time) {
+	var (
+		tx  *sqlx.Tx
+		err error
+	)
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("ERROR: reminders: %v", panicked)
+			log.Print(string(debug.Stack()))
+		}
+	}()
+	due, limit := reminderSchedule(now)
+	if due.IsZero() {
+		return
+	}
+	if tx, err = dbh.Beginx(); err != nil {
+		log.Printf("ERROR: reminders: %s", err)
+		return
+	}
+	defer tx.Rollback()
+	for _, rh := range fetchReminderHosts(tx) {
+		if rh.Email == "" || rh.Reminders >= limit || !rh.LastSent.Before(due) || rh.registered.After(due) {
+			continue
+		}
+		message, err := email.ReminderMessage(tx, rh.host, rh.guests)
+		if err != nil {
+			log.Printf("ERROR: reminder template: %s", err)
+			return
+		}
+		e := email.Queue(tx, rh.Host, "reminder", message)
+		(&model.ReminderSent{HostID: rh.Host, Count: rh.Reminders + 1, EmailID: e.ID, Sent: db.Time{Time: now}}).Save(tx)
+		log.Printf("reminder-sent host=%d email=%q", rh.Host, rh.Email)
+	}
+	if err = tx.Commit(); err != nil {
+		log.Printf("ERROR: reminders: %s", err)
+	}
+}
@@ -0,0 +1,50 @@
+package guest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// scanResult is the response to GET /guests/scan.
+type scanResult struct {
+	Guest  db.ID   `json:"guest"`
+	Party  db.ID   `json:"party"`
+	Table  int     `json:"table"`  // number, zero if not at a numbered table
+	Guests []db.ID `json:"guests"` // everyone in the party, including Guest
+}
+
+// serveScan handles GET /guests/scan?code=${code}, where the code is one
+// scanned from the QR code on a check-in form, badge, or paddle.  It returns
+// the guest the code identifies, and their party, so that the whole party can
+// be checked in at once.  It returns 404 if the code is not valid.
+func serveScan(w *request.ResponseWriter, r *request.Request) {
+	var (
+		guest  *model.Guest
+		result scanResult
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if guest = model.FetchGuestByCode(r.Tx, r.FormValue("code")); guest == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	result.Guest = guest.ID
+	result.Party = guest.PartyID
+	result.Table = model.FetchTable(r.Tx, model.FetchParty(r.Tx, guest.PartyID).TableID).Number
+	result.Guests = []db.ID{}
+	model.FetchGuestsInParty(r.Tx, guest.PartyID, func(g *model.Guest) {
+		result.Guests = append(result.Guests, g.ID)
+	})
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(&result)
+}
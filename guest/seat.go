@@ -0,0 +1,61 @@
+package guest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// serveGuestSeat handles PUT /guest/${gid}/seat.  It takes a JSON {"seat": n}
+// body and moves the guest to that seat at their table.  If another guest is
+// in that seat, the two guests swap seats; or if the guest had no seat, the
+// other guest is given a free one.
+func serveGuestSeat(w *request.ResponseWriter, r *request.Request, guest *model.Guest) {
+	var (
+		body struct {
+			Seat int `json:"seat"`
+		}
+		table *model.Table
+		je    model.JournalEntry
+		err   error
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("serveGuestSeat JSON decode %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	table = model.FetchTable(r.Tx, model.FetchParty(r.Tx, guest.PartyID).TableID)
+	if guest.Cancelled || table.Number == 0 {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if body.Seat < 1 || body.Seat > max(table.EffectiveCapacity(), model.SeatedAtTable(r.Tx, table.ID)) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.Seat == guest.Seat {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	model.FetchGuests(r.Tx, func(g *model.Guest) {
+		g.Seat = guest.Seat
+		g.Save(r.Tx, &je)
+	}, `party IN (SELECT id FROM party WHERE gtable=?) AND seat=?`, table.ID, body.Seat)
+	guest.Seat = body.Seat
+	guest.Save(r.Tx, &je)
+	model.AssignSeats(r.Tx, &je, table)
+	journal.Log(r, &je)
+	w.CommitNoContent(r)
+}
@@ -0,0 +1,334 @@
+package item
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+	"github.com/scholacantorum/gala-backend/spreadsheet"
+)
+
+// itemImportFields are the fields that can be imported from an item catalog.
+// The amount (the fixed price, if any) and value are in dollars and cents.
+var itemImportFields = []spreadsheet.Field{
+	{Name: "lot", Headers: []string{"lot number", "lot #", "lot no"}},
+	{Name: "type", Headers: []string{"item type", "category"}},
+	{Name: "name", Headers: []string{"item", "item name", "title"}},
+	{Name: "description", Headers: []string{"item description"}},
+	{Name: "donor", Headers: []string{"donated by", "donor name"}},
+	{Name: "restrictions", Headers: []string{"restriction", "terms"}},
+	{Name: "amount", Headers: []string{"price", "fixed price"}},
+	{Name: "value", Headers: []string{"fair market value", "fmv", "retail value"}},
+}
+
+// importItemTypes maps the item types accepted in a catalog, reduced to their
+// lower-case letters, to item type values.  Registration items can't be
+// imported; they're set up by hand.
+var importItemTypes = map[string]string{
+	"silent":        model.ItemSilent,
+	"silentauction": model.ItemSilent,
+	"live":          model.ItemLive,
+	"liveauction":   model.ItemLive,
+	"fundaneed":     model.ItemFundANeed,
+	"fan":           model.ItemFundANeed,
+	"donation":      model.ItemDonation,
+	"raffle":        model.ItemRaffle,
+}
+
+// Item import row actions.
+const (
+	importCreate    = "create"
+	importUpdate    = "update"
+	importUnchanged = "unchanged"
+	importError     = "error"
+)
+
+// itemImportResult is the response to POST /items/import.
+type itemImportResult struct {
+	DryRun  bool              `json:"dryRun"`
+	Columns map[string]string `json:"columns"` // field name → column header
+	Rows    []*itemImportRow  `json:"rows"`
+	Errors  int               `json:"errors"`          // number of rows with errors
+	Error   string            `json:"error,omitempty"` // problem with the file as a whole
+}
+
+// itemImportRow is the result of importing one row of an item catalog.
+type itemImportRow struct {
+	Row     int          `json:"row"` // row number in the file
+	Name    string       `json:"name"`
+	Action  string       `json:"action"`
+	Item    db.ID        `json:"item"` // existing item matched, or new item created
+	Changes []itemChange `json:"changes"`
+	Errors  []string     `json:"errors"`
+	item    *model.Item  // with the changes applied
+}
+
+// itemChange is a change to one field of an item, for the preview.  Amounts
+// are shown in dollars.
+type itemChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"` // empty for new items
+	New   string `json:"new"`
+}
+
+// serveItemImport handles POST /items/import, which imports the auction item
+// catalog from a CSV or XLSX spreadsheet.  The request is a multipart form
+// with these fields:
+//
+//	file     the spreadsheet
+//	columns  optional JSON object mapping field names to column headers;
+//	         fields not mapped are found by their usual headers
+//	dryRun   if true, only validate and preview the changes
+//
+// Each row is matched against the existing items by lot number, or failing
+// that, by name (without regard to case).  A row with a lot number matches by
+// name only an item without one.  Matched items are updated with the
+// non-blank cells of their row; other rows create new items, which are silent
+// auction items unless the row gives another type.  The result lists, for
+// each row, the action taken (or to be taken), the changes to each field, and
+// any errors.  If any row has an error, nothing is imported and the status is
+// 400 (unless it is a dry run).  Otherwise, everything is imported in a single
+// journaled transaction.
+func serveItemImport(w *request.ResponseWriter, r *request.Request) {
+	var (
+		result  itemImportResult
+		sheet   *spreadsheet.Sheet
+		mapping map[string]string
+		columns map[string]int
+		je      model.JournalEntry
+		err     error
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	result.DryRun = spreadsheet.Flag(r.FormValue("dryRun"))
+	result.Rows = []*itemImportRow{}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		result.Error = "no spreadsheet file was uploaded"
+		sendItemImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	defer file.Close()
+	if sheet, err = spreadsheet.Read(file); err != nil {
+		result.Error = err.Error()
+		sendItemImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	if cstr := r.FormValue("columns"); cstr != "" {
+		if err = json.Unmarshal([]byte(cstr), &mapping); err != nil {
+			result.Error = "invalid column mapping: " + err.Error()
+			sendItemImportResult(w, http.StatusBadRequest, &result)
+			return
+		}
+	}
+	if columns, err = sheet.Columns(itemImportFields, mapping); err != nil {
+		result.Error = err.Error()
+		sendItemImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	result.Columns = make(map[string]string)
+	for field, col := range columns {
+		result.Columns[field] = sheet.Header[col]
+	}
+	if _, ok := columns["name"]; !ok {
+		result.Error = "no column of item names was found"
+		sendItemImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	planItemImport(r.Tx, sheet, columns, &result)
+	if result.DryRun {
+		sendItemImportResult(w, http.StatusOK, &result)
+		return
+	}
+	if result.Errors != 0 {
+		sendItemImportResult(w, http.StatusBadRequest, &result)
+		return
+	}
+	for _, ir := range result.Rows {
+		if ir.Action != importUnchanged {
+			ir.item.Save(r.Tx, &je)
+		}
+		ir.Item = ir.item.ID
+	}
+	journal.Log(r, &je)
+	if err = r.Tx.Commit(); err != nil {
+		panic(err)
+	}
+	sendItemImportResult(w, http.StatusOK, &result)
+}
+
+// sendItemImportResult sends the result of an item import.
+func sendItemImportResult(w *request.ResponseWriter, status int, result *itemImportResult) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// planItemImport works out what importing each row of an item catalog would
+// do.  It changes nothing in the database.
+func planItemImport(tx *sqlx.Tx, sheet *spreadsheet.Sheet, columns map[string]int, result *itemImportResult) {
+	var (
+		byLot      = make(map[string]*model.Item)
+		byName     = make(map[string][]*model.Item)
+		matchedRow = make(map[db.ID]int)
+		newRow     = make(map[string]int)
+		lotRow     = make(map[string]int)
+	)
+	model.FetchItems(tx, func(i *model.Item) {
+		var copy = *i
+		if i.Lot != "" {
+			byLot[i.Lot] = &copy
+		}
+		byName[strings.ToLower(i.Name)] = append(byName[strings.ToLower(i.Name)], &copy)
+	}, "")
+	for n, row := range sheet.Rows {
+		var (
+			value = func(field string) string { return spreadsheet.Value(row, columns, field) }
+			ir    = itemImportRow{Row: sheet.Numbers[n], Name: value("name")}
+			lot   = value("lot")
+			item  = model.Item{Type: model.ItemSilent}
+		)
+		ir.Changes, ir.Errors = []itemChange{}, []string{}
+		key := strings.ToLower(ir.Name)
+		if lot != "" {
+			if prev, ok := lotRow[lot]; ok {
+				ir.Errors = append(ir.Errors, fmt.Sprintf("lot %s is also on row %d", lot, prev))
+			}
+			lotRow[lot] = ir.Row
+		}
+		matches := byName[key]
+		if lot != "" {
+			// An item with a different lot number is a different item.
+			matches = slices.DeleteFunc(slices.Clone(matches), func(i *model.Item) bool { return i.Lot != "" })
+		}
+		switch {
+		case byLot[lot] != nil:
+			item = *byLot[lot]
+			ir.Item = item.ID
+			if prev, ok := matchedRow[item.ID]; ok {
+				ir.Errors = append(ir.Errors, fmt.Sprintf("same item as row %d", prev))
+			}
+			matchedRow[item.ID] = ir.Row
+		case ir.Name == "":
+			ir.Errors = append(ir.Errors, "the item has no name")
+		case len(matches) > 1:
+			var ids []string
+			for _, m := range matches {
+				ids = append(ids, fmt.Sprint(m.ID))
+			}
+			ir.Errors = append(ir.Errors, fmt.Sprintf("matches more than one existing item (IDs %s)", strings.Join(ids, ", ")))
+		case len(matches) == 1:
+			item = *matches[0]
+			ir.Item = item.ID
+			if prev, ok := matchedRow[item.ID]; ok {
+				ir.Errors = append(ir.Errors, fmt.Sprintf("same item as row %d", prev))
+			}
+			matchedRow[item.ID] = ir.Row
+		default:
+			if lot != "" {
+				break // the lot number check above covers duplicates
+			}
+			if prev, ok := newRow[key]; ok {
+				ir.Errors = append(ir.Errors, fmt.Sprintf("same item as row %d", prev))
+			}
+			newRow[key] = ir.Row
+		}
+
+		// Apply the non-blank cells to the item, noting the changes.  (A
+		// name that differs only in case is a match, not a change.)
+		if ir.Name != "" && !strings.EqualFold(ir.Name, item.Name) {
+			ir.Changes = append(ir.Changes, itemChange{Field: "name", Old: item.Name, New: ir.Name})
+			item.Name = ir.Name
+		}
+		ir.Name = item.Name
+		text := func(field, v string, into *string) {
+			if v != "" && (v != *into || item.ID == 0) {
+				change := itemChange{Field: field, New: v}
+				if item.ID != 0 {
+					change.Old = *into
+				}
+				ir.Changes = append(ir.Changes, change)
+				*into = v
+			}
+		}
+		if v := value("type"); v != "" {
+			t, ok := importItemTypes[letters(v)]
+			switch {
+			case !ok:
+				ir.Errors = append(ir.Errors, fmt.Sprintf("type: %q is not a known item type", v))
+			case t == item.Type:
+				break
+			case item.ID != 0 && typeChangeError(tx, &item) != "":
+				ir.Errors = append(ir.Errors, "type: "+typeChangeError(tx, &item))
+			default:
+				text("type", t, &item.Type)
+			}
+		} else if item.ID == 0 {
+			text("type", item.Type, &item.Type)
+		}
+		text("lot", lot, &item.Lot)
+		text("description", value("description"), &item.Description)
+		text("donor", value("donor"), &item.Donor)
+		text("restrictions", value("restrictions"), &item.Restrictions)
+		dollars := func(field string, into *int) {
+			v := value(field)
+			if v == "" {
+				return
+			}
+			cents, err := spreadsheet.ParseDollars(v)
+			if err != nil {
+				ir.Errors = append(ir.Errors, fmt.Sprintf("%s: %s", field, err))
+				return
+			}
+			if cents != *into || item.ID == 0 {
+				change := itemChange{Field: field, New: spreadsheet.FormatDollars(cents)}
+				if item.ID != 0 {
+					change.Old = spreadsheet.FormatDollars(*into)
+				}
+				ir.Changes = append(ir.Changes, change)
+				*into = cents
+			}
+		}
+		dollars("amount", &item.Amount)
+		dollars("value", &item.Value)
+		switch {
+		case len(ir.Errors) != 0:
+			ir.Action = importError
+			result.Errors++
+		case item.ID == 0:
+			ir.Action = importCreate
+		case len(ir.Changes) != 0:
+			ir.Action = importUpdate
+		default:
+			ir.Action = importUnchanged
+		}
+		ir.item = &item
+		result.Rows = append(result.Rows, &ir)
+	}
+}
+
+// letters returns a string reduced to its lower-case letters.
+func letters(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
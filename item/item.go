@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/jmoiron/sqlx"
+
 	"github.com/scholacantorum/gala-backend/db"
 	"github.com/scholacantorum/gala-backend/journal"
 	"github.com/scholacantorum/gala-backend/model"
@@ -50,15 +52,12 @@ func serveItem(w *request.ResponseWriter, r *request.Request, item *model.Item)
 	}
 }
 
-// deleteItem handles a DELETE /item/${iid} request.
+// deleteItem handles a DELETE /item/${iid} request.  Items that have been
+// purchased can't be deleted, nor can the last registration item.
 func deleteItem(w *request.ResponseWriter, r *request.Request, item *model.Item) {
-	var (
-		hasPurchases bool
-		je           model.JournalEntry
-	)
+	var je model.JournalEntry
 
-	model.FetchPurchases(r.Tx, func(p *model.Purchase) { hasPurchases = true }, `item=?`, item.ID)
-	if hasPurchases {
+	if hasPurchases(r.Tx, item) || isLastRegistrationItem(r.Tx, item) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -79,14 +78,70 @@ func saveItem(w *request.ResponseWriter, r *request.Request, item *model.Item) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if body.ID != item.ID || body.Name == "" || body.Amount < 0 || body.Value < 0 {
+	if body.ID != item.ID || !validItem(r.Tx, &body) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.Type != item.Type && typeChangeError(r.Tx, item) != "" {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	item.Type = body.Type
+	item.Lot = body.Lot
 	item.Name = body.Name
+	item.Description = body.Description
+	item.Donor = body.Donor
+	item.Restrictions = body.Restrictions
 	item.Amount = body.Amount
 	item.Value = body.Value
 	item.Save(r.Tx, &je)
 	journal.Log(r, &je)
 	w.CommitNoContent(r)
 }
+
+// validItem returns whether the item in a PUT or POST request body is valid.
+// Its lot number, if any, must not be used by any other item.
+func validItem(tx *sqlx.Tx, body *model.Item) bool {
+	if body.Name == "" || body.Amount < 0 || body.Value < 0 || !model.ValidItemType(body.Type) {
+		return false
+	}
+	if body.Lot != "" {
+		if other := model.FetchItemByLot(tx, body.Lot); other != nil && other.ID != body.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPurchases returns whether the item has been purchased.
+func hasPurchases(tx *sqlx.Tx, item *model.Item) (found bool) {
+	model.FetchPurchases(tx, func(p *model.Purchase) { found = true }, `item=?`, item.ID)
+	return found
+}
+
+// isLastRegistrationItem returns whether the item is the only registration
+// item.  There must always be one, for registering guests.
+func isLastRegistrationItem(tx *sqlx.Tx, item *model.Item) bool {
+	var others bool
+
+	if item.Type != model.ItemRegistration {
+		return false
+	}
+	model.FetchItems(tx, func(i *model.Item) { others = true }, `type=? AND id!=?`, model.ItemRegistration, item.ID)
+	return !others
+}
+
+// typeChangeError returns the reason why the type of the item can't be
+// changed, or an empty string if it can.  The type of an item that has been
+// purchased can't change, since the receipts, exports, and registrations for
+// those purchases depend on it; and the last registration item must stay one.
+func typeChangeError(tx *sqlx.Tx, item *model.Item) string {
+	switch {
+	case hasPurchases(tx, item):
+		return "the type of an item that has been purchased can't be changed"
+	case isLastRegistrationItem(tx, item):
+		return "the type of the only registration item can't be changed"
+	default:
+		return ""
+	}
+}
@@ -19,6 +19,10 @@ func ServeItems(w *request.ResponseWriter, r *request.Request) {
 	switch head {
 	case "":
 		serveItems(w, r)
+	case "import":
+		serveItemImport(w, r)
+	case "labels.pdf":
+		serveItemLabels(w, r)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -46,7 +50,7 @@ func addItem(w *request.ResponseWriter, r *request.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if body.Name == "" || body.Amount < 0 || body.Value < 0 || body.ID != 0 {
+	if body.ID != 0 || !validItem(r.Tx, &body) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -0,0 +1,76 @@
+package item
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/labels"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+	"github.com/scholacantorum/gala-backend/spreadsheet"
+)
+
+// itemLabels are the labels placed with the items on display.  Each label
+// gets the item's lot number and name, its donor, its value, and its price (if
+// it has a fixed price).
+var itemLabels = labels.Kind{
+	ConfigKey: "itemLabelTemplate",
+	Template:  "avery5163",
+	Fields: []model.LabelField{
+		{Text: "{{with .lot}}Lot {{.}}{{end}}", Size: 12, Bold: true, Align: "C"},
+		{Text: "{{.name}}", Size: 16, Bold: true, Align: "C", Lines: 3},
+		{Text: "{{with .donor}}Donated by {{.}}{{end}}", Size: 10, Align: "C"},
+		{Text: "{{with .value}}Value {{.}}{{end}}", Size: 12, Align: "C"},
+		{Text: "{{with .amount}}Price {{.}}{{end}}", Size: 12, Align: "C"},
+	},
+}
+
+// serveItemLabels handles GET /items/labels.pdf.  It returns a PDF of labels
+// for the auction items and raffle prizes, in alphabetical order.  If "id"
+// query parameters are given, only the labels for those items (of any type)
+// are included.  The "template" query parameter selects the label template;
+// the default is set by the itemLabelTemplate setting in config.json.
+func serveItemLabels(w *request.ResponseWriter, r *request.Request) {
+	var (
+		want  map[db.ID]bool
+		sheet []labels.Label
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	if len(r.Form["id"]) != 0 {
+		want = make(map[db.ID]bool)
+		for _, idstr := range r.Form["id"] {
+			id, err := strconv.Atoi(idstr)
+			if err != nil || id < 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			want[db.ID(id)] = true
+		}
+	}
+	model.FetchItems(r.Tx, func(i *model.Item) {
+		if want != nil && !want[i.ID] {
+			return
+		}
+		if want == nil && !i.IsAuction() && i.Type != model.ItemRaffle {
+			return
+		}
+		label := labels.Label{"id": strconv.Itoa(int(i.ID)), "lot": i.Lot, "name": i.Name, "donor": i.Donor}
+		if i.Amount != 0 {
+			label["amount"] = spreadsheet.FormatDollars(i.Amount)
+		}
+		if i.Value != 0 {
+			label["value"] = spreadsheet.FormatDollars(i.Value)
+		}
+		sheet = append(sheet, label)
+	}, `1 ORDER BY name`)
+	itemLabels.Serve(w, r, "item-labels.pdf", sheet)
+}
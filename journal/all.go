@@ -30,9 +30,11 @@ func ServeAll(w *request.ResponseWriter, r *request.Request) {
 	model.FetchTables(r.Tx, func(t *model.Table) { je.MarkTable(t.ID) }, "")
 	model.FetchParties(r.Tx, func(p *model.Party) { je.MarkParty(p.ID) }, "")
 	model.FetchGuests(r.Tx, func(g *model.Guest) { je.MarkGuest(g.ID) }, "")
+	model.FetchEntrees(r.Tx, func(e *model.Entree) { je.MarkEntree(e.ID) }, "")
 	model.FetchItems(r.Tx, func(i *model.Item) { je.MarkItem(i.ID) }, "")
 	model.FetchPurchases(r.Tx, func(p *model.Purchase) { je.MarkPurchase(p.ID) }, "")
 	je.MarkBidderToGuest()
+	je.BidderNumbering = model.BidderNumbering()
 	je.Populate(r.Tx)
 	if msg.Data, err = json.Marshal(&je); err != nil {
 		panic(err)
@@ -9,8 +9,10 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/jmoiron/sqlx"
 
 	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/db"
 	"github.com/scholacantorum/gala-backend/model"
 	"github.com/scholacantorum/gala-backend/request"
 )
@@ -162,3 +164,51 @@ func Log(r *request.Request, je *model.JournalEntry) {
 	cid, _ = res.LastInsertId()
 	broadcast <- message{int(cid), by}
 }
+
+// GuestsChangedSince returns the IDs of the guests that have been affected by
+// changes journaled since the specified time.  That includes guests who were
+// changed directly, guests in parties that were changed (e.g. moved to another
+// table), and guests at tables that were changed (e.g. renumbered).  Only the
+// journal entries from the first one at or after that time are read.
+func GuestsChangedSince(tx *sqlx.Tx, since time.Time) (guests map[db.ID]bool) {
+	var (
+		change []byte
+		rows   *sql.Rows
+		err    error
+	)
+	guests = make(map[db.ID]bool)
+	if rows, err = tx.Query(`
+SELECT change FROM journal WHERE id >= (SELECT MIN(id) FROM journal WHERE julianday(timestamp) >= julianday(?))
+ORDER BY id`, since.Format(time.RFC3339)); err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		if err = rows.Scan(&change); err != nil {
+			panic(err)
+		}
+		// We only need the IDs of the changed objects.
+		var je struct {
+			Tables  map[db.ID]json.RawMessage `json:"tables"`
+			Parties map[db.ID]json.RawMessage `json:"parties"`
+			Guests  map[db.ID]json.RawMessage `json:"guests"`
+		}
+		if err = json.Unmarshal(change, &je); err != nil {
+			panic(err)
+		}
+		for gid := range je.Guests {
+			guests[gid] = true
+		}
+		for pid := range je.Parties {
+			model.FetchGuestsInParty(tx, pid, func(g *model.Guest) { guests[g.ID] = true })
+		}
+		for tid := range je.Tables {
+			model.FetchPartiesAtTable(tx, tid, func(p *model.Party) {
+				model.FetchGuestsInParty(tx, p.ID, func(g *model.Guest) { guests[g.ID] = true })
+			})
+		}
+	}
+	if err = rows.Err(); err != nil {
+		panic(err)
+	}
+	return guests
+}
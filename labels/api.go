@@ -0,0 +1,112 @@
+package labels
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// templateInfo is the JSON representation of a label template.
+type templateInfo struct {
+	model.LabelTemplate
+	Builtin    bool `json:"builtin"`
+	Customized bool `json:"customized"`
+}
+
+// ServeLabelTemplates handles requests starting with /label-templates.
+func ServeLabelTemplates(w *request.ResponseWriter, r *request.Request) {
+	var name string
+
+	name, r.URL.Path = request.ShiftPath(r.URL.Path)
+	if name == "" {
+		serveLabelTemplates(w, r)
+		return
+	}
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	serveLabelTemplate(w, r, name)
+}
+
+// serveLabelTemplates handles GET /label-templates, which returns all label
+// templates.
+func serveLabelTemplates(w *request.ResponseWriter, r *request.Request) {
+	var templates []*templateInfo
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	for _, name := range templateNames(r.Tx) {
+		templates = append(templates, getTemplateInfo(r, name))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// serveLabelTemplate handles requests to /label-templates/${name}.  PUT
+// creates or replaces a template; DELETE removes a staff-defined template, or
+// reverts a built-in one to its default.
+func serveLabelTemplate(w *request.ResponseWriter, r *request.Request, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		if Template(r.Tx, name) == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(getTemplateInfo(r, name))
+	case http.MethodPut:
+		saveTemplate(w, r, name)
+	case http.MethodDelete:
+		if model.FetchLabelTemplate(r.Tx, name) == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		(&model.LabelTemplate{Name: name}).Delete(r.Tx)
+		w.CommitNoContent(r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func getTemplateInfo(r *request.Request, name string) *templateInfo {
+	var builtin = builtinTemplate(name) != nil
+
+	if t := model.FetchLabelTemplate(r.Tx, name); t != nil {
+		return &templateInfo{*t, builtin, true}
+	}
+	return &templateInfo{*builtinTemplate(name), true, false}
+}
+
+// saveTemplate handles a PUT /label-templates/${name} request.  The template
+// is checked before being saved, and problems with it are returned as 400
+// errors.
+func saveTemplate(w *request.ResponseWriter, r *request.Request, name string) {
+	var (
+		body model.LabelTemplate
+		err  error
+	)
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("saveTemplate JSON decode %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.Name != name {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err = validate(&body); err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err)
+		return
+	}
+	body.Save(r.Tx)
+	w.CommitNoContent(r)
+}
@@ -0,0 +1,339 @@
+package labels
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/qrcode"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// A Label is the data for a single label: the values that its fields can
+// show, keyed by name.
+type Label map[string]string
+
+// A Kind is a kind of label printed by the server, such as program labels.
+type Kind struct {
+	// ConfigKey is the config.json setting that names the template to use
+	// for this kind of label.
+	ConfigKey string
+	// Template is the template to use if config.json doesn't name one.
+	Template string
+	// Fields are the lines printed on each label, for templates that don't
+	// specify their own.
+	Fields []model.LabelField
+}
+
+// Serve renders the supplied labels as a PDF with the specified file name and
+// returns it.  The template is the one named by the "template" query
+// parameter, if any, or the default for the kind of label.  It returns 400 if
+// there is no such template.
+func (k *Kind) Serve(w *request.ResponseWriter, r *request.Request, filename string, labels []Label) {
+	var (
+		name = r.FormValue("template")
+		t    *model.LabelTemplate
+		pdf  *gofpdf.Fpdf
+		buf  bytes.Buffer
+		err  error
+	)
+	if name == "" {
+		name = config.Get(k.ConfigKey)
+	}
+	if name == "" {
+		name = k.Template
+	}
+	if t = Template(r.Tx, name); t == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(t.Fields) == 0 {
+		t.Fields = k.Fields
+	}
+	if pdf, err = render(t, labels); err == nil {
+		err = pdf.Output(&buf)
+	}
+	if err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Write(buf.Bytes())
+}
+
+// parseFields parses the text templates of the fields of a label template.
+func parseFields(fields []model.LabelField) (tmpls []*template.Template, err error) {
+	tmpls = make([]*template.Template, len(fields))
+	for i, f := range fields {
+		tmpls[i], err = template.New(fmt.Sprintf("field%d", i+1)).Option("missingkey=zero").Parse(f.Text)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tmpls, nil
+}
+
+// validate returns an error describing the first problem found with a label
+// template, or nil if it is usable.
+func validate(t *model.LabelTemplate) error {
+	const slop = 0.5 // points, to allow for rounding of fractional inches
+	switch {
+	case t.PageWidth <= 0 || t.PageHeight <= 0:
+		return errors.New("page size must be positive")
+	case t.Rows < 1 || t.Columns < 1:
+		return errors.New("there must be at least one row and one column")
+	case t.Width <= 0 || t.Height <= 0:
+		return errors.New("label size must be positive")
+	case t.Top < 0 || t.Left < 0 || t.ColumnGap < 0 || t.RowGap < 0 || t.PaddingX < 0 || t.PaddingY < 0:
+		return errors.New("margins, gutters, and padding must not be negative")
+	case t.Left+float64(t.Columns)*t.Width+float64(t.Columns-1)*t.ColumnGap > t.PageWidth+slop:
+		return errors.New("labels are too wide for the page")
+	case t.Top+float64(t.Rows)*t.Height+float64(t.Rows-1)*t.RowGap > t.PageHeight+slop:
+		return errors.New("labels are too tall for the page")
+	case 2*t.PaddingX >= t.Width || 2*t.PaddingY >= t.Height:
+		return errors.New("padding leaves no room on the label")
+	}
+	for i, f := range t.Fields {
+		switch {
+		case f.Size <= 0:
+			return fmt.Errorf("field %d: size must be positive", i+1)
+		case f.Height < 0:
+			return fmt.Errorf("field %d: height must not be negative", i+1)
+		case f.Align != "" && f.Align != "L" && f.Align != "C" && f.Align != "R":
+			return fmt.Errorf("field %d: align must be L, C, or R", i+1)
+		case f.Lines < 0:
+			return fmt.Errorf("field %d: lines must not be negative", i+1)
+		case f.Inline && i == 0:
+			return errors.New("field 1: the first field cannot be inline")
+		case f.Inline && f.Lines > 1:
+			return fmt.Errorf("field %d: inline fields cannot wrap", i+1)
+		case f.Inline && f.Banner:
+			return fmt.Errorf("field %d: inline fields cannot be banners", i+1)
+		case f.QR && (f.Lines > 1 || f.Banner):
+			return fmt.Errorf("field %d: QR code fields cannot wrap or be banners", i+1)
+		}
+	}
+	_, err := parseFields(t.Fields)
+	return err
+}
+
+// render renders a set of labels according to a template, which must have
+// fields, and returns the resulting PDF.
+func render(t *model.LabelTemplate, labels []Label) (pdf *gofpdf.Fpdf, err error) {
+	var (
+		tmpls   []*template.Template
+		tr      func(string) string
+		perPage = t.Rows * t.Columns
+	)
+	if tmpls, err = parseFields(t.Fields); err != nil {
+		return nil, err
+	}
+	pdf = gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "P", UnitStr: "pt", Size: gofpdf.SizeType{Wd: t.PageWidth, Ht: t.PageHeight},
+	})
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	for i, label := range labels {
+		var row, col int
+
+		if i%perPage == 0 {
+			pdf.AddPage()
+			if t.HashMarks {
+				renderHashMarks(pdf, t)
+			}
+		}
+		if t.FillDown {
+			col, row = (i%perPage)/t.Rows, (i%perPage)%t.Rows
+		} else {
+			row, col = (i%perPage)/t.Columns, (i%perPage)%t.Columns
+		}
+		x := t.Left + float64(col)*(t.Width+t.ColumnGap)
+		y := t.Top + float64(row)*(t.Height+t.RowGap)
+		if err = renderLabel(pdf, tr, t, tmpls, label, x, y); err != nil {
+			return nil, err
+		}
+	}
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+	return pdf, pdf.Error()
+}
+
+// renderHashMarks draws cutting guides in the top and left margins of the
+// page, at the edges of each column and row of labels.
+func renderHashMarks(pdf *gofpdf.Fpdf, t *model.LabelTemplate) {
+	pdf.SetDrawColor(0, 0, 0)
+	for col := 0; col < t.Columns; col++ {
+		x := t.Left + float64(col)*(t.Width+t.ColumnGap)
+		pdf.Line(x, 0, x, t.Top)
+		pdf.Line(x+t.Width, 0, x+t.Width, t.Top)
+	}
+	for row := 0; row < t.Rows; row++ {
+		y := t.Top + float64(row)*(t.Height+t.RowGap)
+		pdf.Line(0, y, t.Left, y)
+		pdf.Line(0, y+t.Height, t.Left, y+t.Height)
+	}
+}
+
+// renderLabel renders a single label with its top left corner at (x, y).  If
+// the lines don't fit on the label, they are all scaled down to fit.  Before
+// that, any line too wide for the label is wrapped, if its field allows, and
+// then shrunk as needed.
+func renderLabel(
+	pdf *gofpdf.Fpdf, tr func(string) string, t *model.LabelTemplate, tmpls []*template.Template, label Label,
+	x, y float64,
+) error {
+	type line struct {
+		field  model.LabelField
+		text   []string // wrapped
+		size   float64
+		height float64 // of each wrapped line
+	}
+	var (
+		lines []line
+		total float64
+		scale = 1.0
+		width = t.Width - 2*t.PaddingX
+		avail = t.Height - 2*t.PaddingY
+		buf   strings.Builder
+	)
+	for i, f := range t.Fields {
+		buf.Reset()
+		if err := tmpls[i].Execute(&buf, label); err != nil {
+			return err
+		}
+		text := strings.TrimSpace(buf.String())
+		if text == "" {
+			continue
+		}
+		ln := line{field: f, size: f.Size, height: f.Height}
+		switch {
+		case f.QR:
+			ln.size = min(f.Size, width)
+			if ln.height == 0 {
+				ln.height = ln.size
+			}
+			ln.text = []string{text}
+		default:
+			if ln.height == 0 {
+				ln.height = 1.2 * f.Size
+			}
+			ln.text = fitText(pdf, tr, text, f, &ln.size, width)
+		}
+		if f.Inline && len(lines) != 0 {
+			lines = append(lines, ln)
+			continue
+		}
+		if len(ln.text) > 1 {
+			ln.height *= ln.size / f.Size
+		}
+		total += ln.height * float64(len(ln.text))
+		lines = append(lines, ln)
+	}
+	if total > avail {
+		scale = avail / total
+	}
+	y += t.PaddingY
+	if t.Center {
+		y += (avail - total*scale) / 2
+	}
+	var top float64 // of the line being printed
+	for i, ln := range lines {
+		if !ln.field.Inline || i == 0 {
+			top = y
+			y += ln.height * scale * float64(len(ln.text))
+		}
+		align := ln.field.Align
+		if align == "" {
+			align = "L"
+		}
+		if ln.field.QR {
+			qx := x + t.PaddingX
+			switch align {
+			case "C":
+				qx += (width - ln.size*scale) / 2
+			case "R":
+				qx += width - ln.size*scale
+			}
+			qrcode.Draw(pdf, ln.text[0], qx, top, ln.size*scale)
+			continue
+		}
+		pdf.SetFont("helvetica", fontStyle(ln.field), ln.size*scale)
+		valign := "T"
+		if ln.field.Banner {
+			valign = "M"
+			pdf.SetFillColor(0, 0, 0)
+			pdf.Rect(x, top, t.Width, ln.height*scale*float64(len(ln.text)), "F")
+			pdf.SetTextColor(255, 255, 255)
+		}
+		for j, text := range ln.text {
+			pdf.SetXY(x+t.PaddingX, top+float64(j)*ln.height*scale)
+			pdf.CellFormat(width, ln.height*scale, text, "", 0, align+valign, false, 0, "")
+		}
+		pdf.SetTextColor(0, 0, 0)
+	}
+	return nil
+}
+
+// fitText returns the text of a field, wrapped onto as many lines as the
+// field allows and translated for printing, and reduces the font size as
+// needed for it to fit in the specified width.
+func fitText(
+	pdf *gofpdf.Fpdf, tr func(string) string, text string, f model.LabelField, size *float64, width float64,
+) (wrapped []string) {
+	pdf.SetFont("helvetica", fontStyle(f), *size)
+	wrapped = []string{tr(text)}
+	if f.Lines > 1 {
+		for {
+			wrapped = wrapText(pdf, tr, text, width)
+			if len(wrapped) <= f.Lines || *size < 4 {
+				break
+			}
+			*size *= 0.9
+			pdf.SetFontSize(*size)
+		}
+	}
+	for _, line := range wrapped {
+		if w := pdf.GetStringWidth(line); w > width {
+			*size *= width / w
+			pdf.SetFontSize(*size)
+		}
+	}
+	return wrapped
+}
+
+// wrapText breaks text into translated lines that fit in the specified width
+// in the current font, breaking only between words.
+func wrapText(pdf *gofpdf.Fpdf, tr func(string) string, text string, width float64) (lines []string) {
+	var line string
+
+	for _, word := range strings.Fields(text) {
+		if line != "" && pdf.GetStringWidth(tr(line+" "+word)) > width {
+			lines = append(lines, tr(line))
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, tr(line))
+}
+
+func fontStyle(f model.LabelField) string {
+	if f.Bold {
+		return "B"
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+// Package labels prints sheets of labels — program labels, name badges, item
+// labels, and the like — according to label templates.  A template gives the
+// page size, the layout of the labels on the page, and the lines of text
+// printed on each label.  The common Avery sheets are built into the server;
+// staff can adjust them, or define new templates, through the
+// /label-templates API, in which case the templates are stored in the
+// database.
+package labels
+
+import (
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/model"
+)
+
+// builtinTemplates are the label templates built into the server, keyed by
+// name.  They leave the fields to the kind of label being printed.
+var builtinTemplates = map[string]model.LabelTemplate{
+	"program30": {
+		Description: `Letter, 30 per sheet, 1" × 2.625", original program label layout with cutting guides`,
+		PageWidth:   612, PageHeight: 792, Rows: 10, Columns: 3,
+		Top: 36, Left: 11.25, Width: 189, Height: 72, ColumnGap: 11.25,
+		PaddingX: 11.25, PaddingY: 10, FillDown: true, HashMarks: true,
+	},
+	"avery5160": {
+		Description: `Avery 5160 address labels, 30 per sheet, 1" × 2.625"`,
+		PageWidth:   612, PageHeight: 792, Rows: 10, Columns: 3,
+		Top: 36, Left: 13.5, Width: 189, Height: 72, ColumnGap: 9,
+		PaddingX: 9, PaddingY: 6,
+	},
+	"avery5161": {
+		Description: `Avery 5161 address labels, 20 per sheet, 1" × 4"`,
+		PageWidth:   612, PageHeight: 792, Rows: 10, Columns: 2,
+		Top: 36, Left: 11.25, Width: 288, Height: 72, ColumnGap: 13.5,
+		PaddingX: 12, PaddingY: 6,
+	},
+	"avery5163": {
+		Description: `Avery 5163 shipping labels, 10 per sheet, 2" × 4"`,
+		PageWidth:   612, PageHeight: 792, Rows: 5, Columns: 2,
+		Top: 36, Left: 11.25, Width: 288, Height: 144, ColumnGap: 13.5,
+		PaddingX: 12, PaddingY: 12, Center: true,
+	},
+	"avery5164": {
+		Description: `Avery 5164 shipping labels, 6 per sheet, 3.33" × 4"`,
+		PageWidth:   612, PageHeight: 792, Rows: 3, Columns: 2,
+		Top: 36, Left: 11.25, Width: 288, Height: 240, ColumnGap: 13.5,
+		PaddingX: 18, PaddingY: 18, Center: true,
+	},
+	"avery5167": {
+		Description: `Avery 5167 return address labels, 80 per sheet, 0.5" × 1.75"`,
+		PageWidth:   612, PageHeight: 792, Rows: 20, Columns: 4,
+		Top: 36, Left: 21.6, Width: 126, Height: 36, ColumnGap: 21.6,
+		PaddingX: 4, PaddingY: 2, Center: true,
+	},
+	"avery5392": {
+		Description: `Avery 5392 name badge inserts, 6 per sheet, 3" × 4"`,
+		PageWidth:   612, PageHeight: 792, Rows: 3, Columns: 2,
+		Top: 72, Left: 18, Width: 288, Height: 216,
+		PaddingX: 18, PaddingY: 18, Center: true,
+	},
+	"avery5395": {
+		Description: `Avery 5395 adhesive name badges, 8 per sheet, 2.33" × 3.375"`,
+		PageWidth:   612, PageHeight: 792, Rows: 4, Columns: 2,
+		Top: 42, Left: 43, Width: 243, Height: 168, ColumnGap: 40, RowGap: 12,
+		PaddingX: 12, PaddingY: 12, Center: true,
+	},
+}
+
+// builtinTemplate returns the built-in template with the specified name, or
+// nil if there is none.
+func builtinTemplate(name string) (t *model.LabelTemplate) {
+	if bt, ok := builtinTemplates[name]; ok {
+		bt.Name = name
+		bt.Fields = []model.LabelField{}
+		return &bt
+	}
+	return nil
+}
+
+// Template returns the named label template: the staff-defined version if
+// there is one, or the built-in one otherwise.  It returns nil if there is no
+// such template.
+func Template(tx *sqlx.Tx, name string) (t *model.LabelTemplate) {
+	if t = model.FetchLabelTemplate(tx, name); t != nil {
+		return t
+	}
+	return builtinTemplate(name)
+}
+
+// templateNames returns the names of all label templates, built-in and
+// staff-defined, in sorted order.
+func templateNames(tx *sqlx.Tx) (names []string) {
+	for name := range builtinTemplates {
+		names = append(names, name)
+	}
+	model.FetchLabelTemplates(tx, func(t *model.LabelTemplate) {
+		if _, ok := builtinTemplates[t.Name]; !ok {
+			names = append(names, t.Name)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
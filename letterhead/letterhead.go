@@ -0,0 +1,52 @@
+// Package letterhead creates PDF documents on Schola Cantorum letterhead: the
+// receipt logo at the top of each page, and the organization's address and tax
+// status at the bottom.  It is used for receipts, so that every form of
+// receipt looks the same.
+package letterhead
+
+import (
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Page geometry, in points.  Content should be placed between Left and Right,
+// and between Top and Bottom.
+const (
+	Left   = 54.0
+	Right  = 558.0
+	Width  = Right - Left
+	Top    = 126.0
+	Bottom = 720.0
+)
+
+// logoFile is the logo placed at the top of each page.  It is read from the
+// site root, which is the server's working directory.
+const logoFile = "receipt-logo.png"
+
+var footer = [2]string{
+	"650-B Fremont Avenue, Suite 321 • Los Altos CA 94024 • ScholaCantorum.org • (650) 254-1700",
+	"Info@ScholaCantorum.org • Schola Cantorum is a 501(c)(3) nonprofit organization, tax ID 94-2597822",
+}
+
+// New creates a new, empty PDF document on letterhead.  Pages added to it with
+// AddPage will have the letterhead drawn on them automatically, and automatic
+// page breaks will keep content clear of the footer.  The returned translator
+// converts UTF-8 strings to the encoding used by the document's fonts; all
+// text should be passed through it.
+func New() (pdf *gofpdf.Fpdf, tr func(string) string) {
+	pdf = gofpdf.New("P", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(Left, Top, 612-Right)
+	pdf.SetAutoPageBreak(true, 792-Bottom)
+	pdf.SetHeaderFunc(func() {
+		pdf.ImageOptions(logoFile, Left, 36, 0, 72, false, gofpdf.ImageOptions{}, 0, "")
+		pdf.SetXY(Left, Top)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetFont("helvetica", "", 9)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetXY(Left, 792-54)
+		pdf.CellFormat(Width, 11, tr(footer[0]), "", 2, "CM", false, 0, "")
+		pdf.CellFormat(Width, 11, tr(footer[1]), "", 2, "CM", false, 0, "")
+	})
+	return pdf, tr
+}
@@ -25,6 +25,7 @@ import (
 	"os/signal"
 	"path"
 	"runtime/debug"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -34,9 +35,12 @@ import (
 	"github.com/scholacantorum/gala-backend/authn"
 	"github.com/scholacantorum/gala-backend/config"
 	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/email"
+	"github.com/scholacantorum/gala-backend/entree"
 	"github.com/scholacantorum/gala-backend/guest"
 	"github.com/scholacantorum/gala-backend/item"
 	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/labels"
 	"github.com/scholacantorum/gala-backend/party"
 	"github.com/scholacantorum/gala-backend/payments"
 	"github.com/scholacantorum/gala-backend/purchase"
@@ -99,6 +103,8 @@ func main() {
 		wg.Done()
 	}()
 	go journal.Sender()
+	go email.Sender(dbh, &requestMutex)
+	go guest.Reminders(dbh, &requestMutex)
 	log.Printf("SERVER START")
 	go func() {
 		err := server2.ServeTLS(tcpKeepAliveListener{listener2.(*net.TCPListener)}, "cert.pem", "key.pem")
@@ -120,10 +126,12 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	origin := config.Get("webSocketOrigin")
 	if r.URL.Path == "/register" {
 		origin = config.Get("registerOrigin")
+	} else if strings.HasPrefix(r.URL.Path, "/portal/") {
+		origin = guest.PortalOrigin()
 	}
 	w.Header().Set("Access-Control-Allow-Origin", origin)
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
-	w.Header().Set("Access-Control-Allow-Headers", "auth")
+	w.Header().Set("Access-Control-Allow-Headers", "auth, content-type")
 	w.Header().Set("Access-Control-Expose-Headers", "auth")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
 	if r.Method == http.MethodOptions {
@@ -200,7 +208,7 @@ func transactionWrapper(w *request.ResponseWriter, r *request.Request) {
 // authChecker checks the authentication of the caller.
 func authChecker(w *request.ResponseWriter, r *request.Request) {
 	r.URL.Path = path.Clean(r.URL.Path)
-	if r.URL.Path != "/login" && r.URL.Path != "/register" {
+	if r.URL.Path != "/login" && r.URL.Path != "/register" && !strings.HasPrefix(r.URL.Path, "/portal/") {
 		if !authn.ValidSession(r) {
 			log.Printf("reject unauthorized")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -221,6 +229,14 @@ func router(w *request.ResponseWriter, r *request.Request) {
 	switch head {
 	case "all":
 		journal.ServeAll(w, r)
+	case "email":
+		email.ServeEmail(w, r)
+	case "emails":
+		email.ServeEmails(w, r)
+	case "entree":
+		entree.ServeEntree(w, r)
+	case "entrees":
+		entree.ServeEntrees(w, r)
 	case "guest":
 		guest.ServeGuest(w, r)
 	case "guests":
@@ -229,12 +245,16 @@ func router(w *request.ResponseWriter, r *request.Request) {
 		item.ServeItem(w, r)
 	case "items":
 		item.ServeItems(w, r)
+	case "label-templates":
+		labels.ServeLabelTemplates(w, r)
 	case "login":
 		authn.ServeLogin(w, r)
 	case "party":
 		party.ServeParty(w, r)
 	case "payments":
 		payments.ServePayments(w, r)
+	case "portal":
+		guest.ServePortal(w, r)
 	case "purchase":
 		purchase.ServePurchase(w, r)
 	case "purchases":
@@ -243,6 +263,8 @@ func router(w *request.ResponseWriter, r *request.Request) {
 		guest.ServeRegister(w, r)
 	case "table":
 		table.ServeTable(w, r)
+	case "tables":
+		table.ServeTables(w, r)
 	case "ws":
 		r.Tx.Rollback()
 		requestMutex.Unlock()
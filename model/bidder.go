@@ -1,31 +1,166 @@
 package model
 
 import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/jmoiron/sqlx"
 
 	"github.com/scholacantorum/gala-backend/config"
 	"github.com/scholacantorum/gala-backend/db"
 )
 
+// A bidderScheme is a way of numbering bidders.  The scheme in use is chosen
+// by the bidderNumbering setting in config.json.
+type bidderScheme interface {
+	// assign ensures that all guests at numbered tables have valid bidder
+	// numbers, and that no one else does.
+	assign(tx *sqlx.Tx, je *JournalEntry)
+	// format returns the printed form of a (nonzero) bidder number.
+	format(bidder int) string
+	// parse returns the bidder number with the printed form s.
+	parse(s string) (bidder int, err error)
+}
+
+// bidderSchemes is the set of bidder numbering schemes, keyed by their names
+// in config.json.
+var bidderSchemes = map[string]bidderScheme{
+	// Table 12 gets bidder numbers 0x120 through 0x12F.  This is the
+	// default, for compatibility with paddles printed in past years.
+	"hex": &tableBidderScheme{
+		first: func(table int) int { return ((table/10)*16 + table%10) * 16 },
+		count: 16,
+		base:  16,
+	},
+	// Table 12 gets bidder numbers 1201 through 1299.
+	"decimal": &tableBidderScheme{
+		first: func(table int) int { return table*100 + 1 },
+		count: 99,
+		base:  10,
+	},
+	// Guests are numbered from 1, in alphabetical order, regardless of
+	// table.
+	"sequential": sequentialBidderScheme{},
+}
+
+// BidderNumbering returns the name of the bidder numbering scheme in use.
+func BidderNumbering() string {
+	if name := config.Get("bidderNumbering"); bidderSchemes[name] != nil {
+		return name
+	}
+	return "hex"
+}
+
+func currentBidderScheme() bidderScheme {
+	return bidderSchemes[BidderNumbering()]
+}
+
+// FormatBidder returns the printed form of a bidder number, according to the
+// bidder numbering scheme in use.  It returns an empty string for zero (no
+// bidder number).  All labels, forms, and reports showing bidder numbers
+// should use it.
+func FormatBidder(bidder int) string {
+	if bidder == 0 {
+		return ""
+	}
+	return currentBidderScheme().format(bidder)
+}
+
+// ParseBidder returns the bidder number with the specified printed form,
+// according to the bidder numbering scheme in use.  It is the inverse of
+// FormatBidder: an empty string is zero (no bidder number).
+func ParseBidder(s string) (bidder int, err error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, nil
+	}
+	if bidder, err = currentBidderScheme().parse(s); err == nil && bidder < 1 {
+		err = fmt.Errorf("%q is not a bidder number", s)
+	}
+	return bidder, err
+}
+
 // updateBidderNumbers ensures that all guests have bidder numbers appropriate
 // for their tables.  It does not change bidder numbers unless they are wrong
 // for their tables.  It does nothing if the autoBidderNumbers flag in the
-// configuration has been turned off (which is done when materials with bidder
-// numbers on them have been printed).
+// configuration has been turned off, or if bidder numbers have been frozen
+// (which is done when materials with bidder numbers on them have been
+// printed).  When the numbering scheme has changed since bidder numbers were
+// last assigned, everyone gets a new number under the new scheme.
 func updateBidderNumbers(tx *sqlx.Tx, je *JournalEntry) {
-	if config.Get("autoBidderNumbers") != "true" {
+	if config.Get("autoBidderNumbers") != "true" || !BiddersFrozen(tx).IsZero() {
 		return
 	}
+	if name := BidderNumbering(); assignedBidderScheme(tx) != name {
+		clearBidderNumbers(tx, je)
+		tx.MustExec(`INSERT OR REPLACE INTO bidderScheme (id, name) VALUES (1,?)`, name)
+	}
+	currentBidderScheme().assign(tx, je)
+}
+
+// assignedBidderScheme returns the name of the bidder numbering scheme under
+// which the current bidder numbers were assigned.
+func assignedBidderScheme(tx *sqlx.Tx) (name string) {
+	switch err := tx.QueryRow(`SELECT name FROM bidderScheme WHERE id=1`).Scan(&name); err {
+	case nil:
+		return name
+	case sql.ErrNoRows:
+		return "hex" // the only scheme before the scheme was configurable
+	default:
+		panic(err)
+	}
+}
+
+// clearBidderNumbers removes everyone's bidder number.
+func clearBidderNumbers(tx *sqlx.Tx, je *JournalEntry) {
+	var ids []db.ID
+
+	if err := tx.Select(&ids, `SELECT id FROM guest WHERE bidder!=0`); err != nil {
+		panic(err)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	for _, id := range ids {
+		je.MarkGuest(id)
+	}
+	je.MarkBidderToGuest()
+	tx.MustExec(`UPDATE guest SET bidder=0 WHERE bidder!=0`)
+}
+
+// tableBidderScheme is a bidder numbering scheme in which each table has its
+// own range of bidder numbers.
+type tableBidderScheme struct {
+	first func(table int) int // first bidder number for the table
+	count int                 // number of bidder numbers per table
+	base  int                 // numeric base for printing
+}
+
+func (s *tableBidderScheme) assign(tx *sqlx.Tx, je *JournalEntry) {
 	FetchTables(tx, func(table *Table) {
-		updateBidderNumbersAtTable(tx, je, table)
+		s.assignAtTable(tx, je, table)
 	}, "")
 }
 
-func updateBidderNumbersAtTable(tx *sqlx.Tx, je *JournalEntry, table *Table) {
+func (s *tableBidderScheme) format(bidder int) string {
+	return strings.ToUpper(strconv.FormatInt(int64(bidder), s.base))
+}
+
+func (s *tableBidderScheme) parse(str string) (bidder int, err error) {
+	var b64 int64
+
+	b64, err = strconv.ParseInt(str, s.base, 0)
+	return int(b64), err
+}
+
+func (s *tableBidderScheme) assignAtTable(tx *sqlx.Tx, je *JournalEntry, table *Table) {
 	var (
 		toadjust []*Guest
 		bidders  = make(map[db.ID]int)
 		used     = make(map[int]bool)
+		first    = s.first(table.Number)
 	)
 	FetchPartiesAtTable(tx, table.ID, func(p *Party) {
 		FetchGuestsInParty(tx, p.ID, func(g *Guest) {
@@ -36,7 +171,7 @@ func updateBidderNumbersAtTable(tx *sqlx.Tx, je *JournalEntry, table *Table) {
 				g.Bidder = 0
 				g.Save(tx, je)
 				je.MarkBidderToGuest()
-			case g.Bidder/16 == tableNumberToBidderBase(table.Number): // valid bidder number for table
+			case g.Bidder >= first && g.Bidder < first+s.count: // valid bidder number for table
 				bidders[g.ID] = g.Bidder
 				used[g.Bidder] = true
 			default: // bidder number doesn't match table
@@ -47,7 +182,7 @@ func updateBidderNumbersAtTable(tx *sqlx.Tx, je *JournalEntry, table *Table) {
 	})
 	for _, g := range toadjust { // assign to self-payers first
 		if g.PayerID == 0 {
-			g.Bidder = updateBidderNumberNextAvail(used, table.Number)
+			g.Bidder = nextAvailBidder(used, first)
 			g.Save(tx, je)
 			bidders[g.ID] = g.Bidder
 		}
@@ -59,7 +194,7 @@ func updateBidderNumbersAtTable(tx *sqlx.Tx, je *JournalEntry, table *Table) {
 				// bidder numbers.
 				g.Bidder = b
 			} else {
-				g.Bidder = updateBidderNumberNextAvail(used, table.Number)
+				g.Bidder = nextAvailBidder(used, first)
 			}
 			g.Save(tx, je)
 		}
@@ -69,13 +204,78 @@ func updateBidderNumbersAtTable(tx *sqlx.Tx, je *JournalEntry, table *Table) {
 	}
 }
 
-func updateBidderNumberNextAvail(used map[int]bool, table int) (bidder int) {
-	for bidder = tableNumberToBidderBase(table) * 16; used[bidder]; bidder++ {
+// nextAvailBidder returns the first unused bidder number starting at first,
+// and marks it used.
+func nextAvailBidder(used map[int]bool, first int) (bidder int) {
+	for bidder = first; used[bidder]; bidder++ {
 	}
 	used[bidder] = true
 	return bidder
 }
 
-func tableNumberToBidderBase(tnum int) int {
-	return (tnum/10)*16 + (tnum % 10)
+// sequentialBidderScheme is a bidder numbering scheme in which bidder numbers
+// are assigned sequentially, without regard to table.  Guests who need
+// numbers get them in alphabetical order, after all of the numbers already
+// in use, so that existing numbers never change.  (Numbers left over from
+// another scheme are cleared by updateBidderNumbers before we get here.)
+type sequentialBidderScheme struct{}
+
+func (sequentialBidderScheme) assign(tx *sqlx.Tx, je *JournalEntry) {
+	var (
+		toadjust []*Guest
+		bidders  = make(map[db.ID]int)
+		tables   = make(map[db.ID]int)
+		parties  = make(map[db.ID]int)
+		next     = 1
+	)
+	FetchTables(tx, func(t *Table) { tables[t.ID] = t.Number }, "")
+	FetchParties(tx, func(p *Party) { parties[p.ID] = tables[p.TableID] }, "")
+	FetchGuests(tx, func(g *Guest) {
+		seated := parties[g.PartyID] != 0
+		switch {
+		case !seated && g.Bidder == 0: // no change needed
+			break
+		case !seated && g.Bidder != 0: // remove bidder number since not at table
+			g.Bidder = 0
+			g.Save(tx, je)
+			je.MarkBidderToGuest()
+		case g.Bidder != 0: // valid bidder number
+			bidders[g.ID] = g.Bidder
+			next = max(next, g.Bidder+1)
+		default:
+			gcopy := *g
+			toadjust = append(toadjust, &gcopy)
+		}
+	}, "")
+	sort.SliceStable(toadjust, func(i, j int) bool { return toadjust[i].Sortname < toadjust[j].Sortname })
+	for _, g := range toadjust { // assign to self-payers first
+		if g.PayerID == 0 {
+			g.Bidder, next = next, next+1
+			g.Save(tx, je)
+			bidders[g.ID] = g.Bidder
+		}
+	}
+	for _, g := range toadjust { // then to non-self-payers
+		if g.PayerID != 0 {
+			if b := bidders[g.PayerID]; b != 0 {
+				// Their payer is attending; share bidder
+				// numbers.
+				g.Bidder = b
+			} else {
+				g.Bidder, next = next, next+1
+			}
+			g.Save(tx, je)
+		}
+	}
+	if len(toadjust) != 0 {
+		je.MarkBidderToGuest()
+	}
+}
+
+func (sequentialBidderScheme) format(bidder int) string {
+	return strconv.Itoa(bidder)
+}
+
+func (sequentialBidderScheme) parse(s string) (bidder int, err error) {
+	return strconv.Atoi(s)
 }
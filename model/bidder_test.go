@@ -0,0 +1,209 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/db/dbtest"
+)
+
+func TestMain(m *testing.M) {
+	// The model reads config.json from the current directory.  Bidder
+	// numbers are not assigned automatically, so that each test can run the
+	// scheme it's testing.
+	dir, err := os.MkdirTemp("", "model-test")
+	if err != nil {
+		panic(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"bidderNumbering": "hex"}`), 0644); err != nil {
+		panic(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// bidders returns the bidder numbers of all guests, keyed by guest ID.
+func bidders(tx *sqlx.Tx) map[db.ID]int {
+	var got = make(map[db.ID]int)
+	FetchGuests(tx, func(g *Guest) { got[g.ID] = g.Bidder }, "")
+	return got
+}
+
+func TestFormatBidder(t *testing.T) {
+	tests := []struct {
+		scheme string
+		bidder int
+		want   string
+	}{
+		{"hex", 0x120, "120"},
+		{"hex", 0x12F, "12F"},
+		{"decimal", 1201, "1201"},
+		{"sequential", 7, "7"},
+	}
+	for _, tt := range tests {
+		if got := bidderSchemes[tt.scheme].format(tt.bidder); got != tt.want {
+			t.Errorf("%s format(%d) = %q; want %q", tt.scheme, tt.bidder, got, tt.want)
+		}
+	}
+	if got := FormatBidder(0); got != "" {
+		t.Errorf("FormatBidder(0) = %q; want empty", got)
+	}
+	if got := FormatBidder(0x3A); got != "3A" {
+		t.Errorf("FormatBidder(0x3A) = %q; want 3A", got)
+	}
+}
+
+func TestParseBidder(t *testing.T) {
+	for _, scheme := range []string{"hex", "decimal", "sequential"} {
+		for _, bidder := range []int{1, 7, 0x10, 0x12F, 101, 1201, 1299, 4095} {
+			s := bidderSchemes[scheme]
+			if got, err := s.parse(s.format(bidder)); err != nil || got != bidder {
+				t.Errorf("%s parse(format(%d)) = %d, %v", scheme, bidder, got, err)
+			}
+		}
+	}
+	tests := []struct {
+		s      string
+		bidder int
+		err    bool
+	}{
+		{"12f", 0x12F, false},
+		{" 3A ", 0x3A, false},
+		{"", 0, false},
+		{"0", 0, true},
+		{"-5", 0, true},
+		{"12G", 0, true},
+	}
+	for _, tt := range tests {
+		if got, err := ParseBidder(tt.s); (err != nil) != tt.err || (err == nil && got != tt.bidder) {
+			t.Errorf("ParseBidder(%q) = %d, %v; want %d, error %v", tt.s, got, err, tt.bidder, tt.err)
+		}
+	}
+	if got, err := ParseBidder(FormatBidder(0)); err != nil || got != 0 {
+		t.Errorf("ParseBidder(FormatBidder(0)) = %d, %v", got, err)
+	}
+}
+
+func TestBidderRanges(t *testing.T) {
+	tests := []struct {
+		scheme string
+		table  int
+		first  int
+		count  int
+	}{
+		{"hex", 1, 0x10, 16},
+		{"hex", 12, 0x120, 16},
+		{"decimal", 1, 101, 99},
+		{"decimal", 12, 1201, 99},
+	}
+	for _, tt := range tests {
+		s := bidderSchemes[tt.scheme].(*tableBidderScheme)
+		if first := s.first(tt.table); first != tt.first || s.count != tt.count {
+			t.Errorf("%s table %d: first %d, count %d; want %d, %d", tt.scheme, tt.table, first, s.count, tt.first, tt.count)
+		}
+	}
+}
+
+// setUpBidders creates table 12 and an unnumbered table, with these guests:
+//
+//	1 Ann Able   table 12, self-paying, already has a number
+//	2 Bob Baker  table 12, self-paying, no number
+//	3 Cal Able   table 12, paid for by Ann
+//	4 Dee Dunn   unnumbered table, has a number
+//	5 Eve Evans  table 12, self-paying, has a number that doesn't fit
+//	6 Fay Fox    table 12, paid for by Dee
+func setUpBidders(tx *sqlx.Tx, ann, dee, eve int) {
+	tx.MustExec(`INSERT INTO gtable (id, num) VALUES (1,0), (2,12)`)
+	tx.MustExec(`INSERT INTO party (id, gtable, place) VALUES (1,2,1), (2,1,1)`)
+	tx.MustExec(`
+INSERT INTO guest (id, name, sortname, party, bidder, payer) VALUES
+    (1, 'Ann Able',  'Able, Ann',   1, ?, NULL),
+    (2, 'Bob Baker', 'Baker, Bob',  1, 0, NULL),
+    (3, 'Cal Able',  'Able, Cal',   1, 0, 1),
+    (4, 'Dee Dunn',  'Dunn, Dee',   2, ?, NULL),
+    (5, 'Eve Evans', 'Evans, Eve',  1, ?, NULL),
+    (6, 'Fay Fox',   'Fox, Fay',    1, 0, 4)`, ann, dee, eve)
+}
+
+func TestTableBidderScheme(t *testing.T) {
+	tests := []struct {
+		scheme        string
+		ann, dee, eve int
+		want          map[db.ID]int
+	}{
+		{"hex", 0x125, 0x130, 0x1301, map[db.ID]int{1: 0x125, 2: 0x120, 3: 0x125, 4: 0, 5: 0x121, 6: 0x122}},
+		{"decimal", 1205, 1301, 0x125, map[db.ID]int{1: 1205, 2: 1201, 3: 1205, 4: 0, 5: 1202, 6: 1203}},
+	}
+	for _, tt := range tests {
+		var je JournalEntry
+		tx := dbtest.OpenTx(t)
+		setUpBidders(tx, tt.ann, tt.dee, tt.eve)
+		bidderSchemes[tt.scheme].assign(tx, &je)
+		got := bidders(tx)
+		for id, want := range tt.want {
+			if got[id] != want {
+				t.Errorf("%s: guest %d has bidder %s; want %s", tt.scheme, id,
+					bidderSchemes[tt.scheme].format(got[id]), bidderSchemes[tt.scheme].format(want))
+			}
+		}
+		if je.BidderToGuest == nil {
+			t.Errorf("%s: bidder numbers not marked changed", tt.scheme)
+		}
+	}
+}
+
+func TestSequentialBidderScheme(t *testing.T) {
+	var je JournalEntry
+	tx := dbtest.OpenTx(t)
+	setUpBidders(tx, 5, 9, 2)
+	bidderSchemes["sequential"].assign(tx, &je)
+	// Ann and Eve keep their numbers; Dee loses hers, since she isn't at a
+	// numbered table.  Bob and Fay get numbers after the highest still in
+	// use, Bob first since he's the self-payer, and Cal shares Ann's.
+	want := map[db.ID]int{1: 5, 2: 6, 3: 5, 4: 0, 5: 2, 6: 7}
+	got := bidders(tx)
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("guest %d has bidder %d; want %d", id, got[id], w)
+		}
+	}
+}
+
+func TestBidderSchemeChange(t *testing.T) {
+	var je JournalEntry
+	tx := dbtest.OpenTx(t)
+	setUpBidders(tx, 0x125, 0x130, 0x121)
+	if got := assignedBidderScheme(tx); got != "hex" {
+		t.Errorf("assignedBidderScheme() = %q with no record; want hex", got)
+	}
+	tx.MustExec(`INSERT INTO bidderScheme (id, name) VALUES (1, 'sequential')`)
+	if got := assignedBidderScheme(tx); got != "sequential" {
+		t.Errorf("assignedBidderScheme() = %q; want sequential", got)
+	}
+	clearBidderNumbers(tx, &je)
+	for id, b := range bidders(tx) {
+		if b != 0 {
+			t.Errorf("guest %d still has bidder %d", id, b)
+		}
+	}
+	if len(je.Guests) != 3 || je.BidderToGuest == nil {
+		t.Errorf("journal marks %d guests, bidders %v; want 3 and marked", len(je.Guests), je.BidderToGuest != nil)
+	}
+	// Once cleared, the sequential scheme numbers everyone alphabetically.
+	bidderSchemes["sequential"].assign(tx, &je)
+	want := map[db.ID]int{1: 1, 2: 2, 3: 1, 4: 0, 5: 3, 6: 4}
+	got := bidders(tx)
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("guest %d has bidder %d after renumbering; want %d", id, got[id], w)
+		}
+	}
+}
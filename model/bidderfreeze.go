@@ -0,0 +1,69 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+)
+
+// BidderSnapshot records a guest's bidder number, name, and table number at the
+// time bidder numbers were frozen.  See db/schema.sql for details.
+type BidderSnapshot struct {
+	GuestID db.ID  `json:"guest" db:"guest"`
+	Bidder  int    `json:"bidder" db:"bidder"`
+	Name    string `json:"name" db:"name"`
+	Table   int    `json:"table" db:"tnum"`
+}
+
+// FreezeBidders takes a snapshot of every guest's bidder number, name, and
+// table number, replacing any previous snapshot, and freezes bidder numbers
+// so that they are no longer changed automatically.
+func FreezeBidders(tx *sqlx.Tx) {
+	tx.MustExec(`DELETE FROM bidderSnapshot`)
+	tx.MustExec(`
+INSERT INTO bidderSnapshot (guest, bidder, name, tnum)
+SELECT g.id, g.bidder, g.name, t.num FROM guest g, party p, gtable t WHERE g.party=p.id AND p.gtable=t.id`)
+	tx.MustExec(`INSERT OR REPLACE INTO bidderFreeze (id, frozen) VALUES (1,?)`, db.Time{Time: time.Now()})
+}
+
+// UnfreezeBidders allows bidder numbers to be changed automatically again.
+// The snapshot is kept, so that changes made since can still be reported.
+func UnfreezeBidders(tx *sqlx.Tx) {
+	tx.MustExec(`DELETE FROM bidderFreeze`)
+}
+
+// BiddersFrozen returns the time at which bidder numbers were frozen, or a
+// zero time if they are not frozen.
+func BiddersFrozen(tx *sqlx.Tx) (frozen db.Time) {
+	switch err := tx.QueryRow(`SELECT frozen FROM bidderFreeze WHERE id=1`).Scan(&frozen); err {
+	case nil, sql.ErrNoRows:
+		return frozen
+	default:
+		panic(err)
+	}
+}
+
+// FetchBidderSnapshots calls the supplied function with each guest snapshot
+// taken when bidder numbers were last frozen, in guest ID order.
+func FetchBidderSnapshots(tx *sqlx.Tx, fn func(*BidderSnapshot)) {
+	var (
+		bs   BidderSnapshot
+		rows *sqlx.Rows
+		err  error
+	)
+	if rows, err = tx.Queryx(`SELECT * FROM bidderSnapshot ORDER BY guest`); err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		if err = rows.StructScan(&bs); err != nil {
+			panic(err)
+		}
+		fn(&bs)
+	}
+	if err = rows.Err(); err != nil {
+		panic(err)
+	}
+}
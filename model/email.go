@@ -0,0 +1,99 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+)
+
+// Email status values.
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailFailed  = "failed"
+)
+
+// Email represents an outgoing email message.  See db/schema.sql for details.
+type Email struct {
+	ID          db.ID   `json:"id" db:"id"`
+	GuestID     db.ID   `json:"guest" db:"guest"`
+	Template    string  `json:"template" db:"template"`
+	Recipient   string  `json:"recipient" db:"recipient"`
+	Subject     string  `json:"subject" db:"subject"`
+	Message     []byte  `json:"-" db:"message"`
+	Status      string  `json:"status" db:"status"`
+	Attempts    int     `json:"attempts" db:"attempts"`
+	NextAttempt db.Time `json:"nextAttempt" db:"nextAttempt"`
+	LastError   string  `json:"lastError" db:"lastError"`
+	Created     db.Time `json:"created" db:"created"`
+	Sent        db.Time `json:"sent" db:"sent"`
+}
+
+// Save saves an email to the database.  Emails are not part of the JSON
+// journal.
+func (e *Email) Save(tx *sqlx.Tx) {
+	var (
+		res sql.Result
+		nid int64
+		err error
+	)
+	res, err = tx.Exec(`
+INSERT OR REPLACE INTO email (id, guest, template, recipient, subject, message, status, attempts, nextAttempt, lastError,
+    created, sent) VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`,
+		e.ID, e.GuestID, e.Template, e.Recipient, e.Subject, e.Message, e.Status, e.Attempts, e.NextAttempt,
+		e.LastError, e.Created, e.Sent)
+	if err != nil {
+		panic(err)
+	}
+	if e.ID == 0 {
+		if nid, err = res.LastInsertId(); err != nil {
+			panic(err)
+		} else {
+			e.ID = db.ID(nid)
+		}
+	}
+}
+
+// FetchEmail returns the email with the specified ID.  It returns nil if the
+// email does not exist.
+func FetchEmail(tx *sqlx.Tx, id db.ID) (e *Email) {
+	e = new(Email)
+	switch err := tx.Get(e, `SELECT * FROM email WHERE id=?`, id); err {
+	case nil:
+		return e
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// FetchEmails calls the supplied function with each email that matches the
+// supplied criteria.  The emails are retrieved in order of creation.
+func FetchEmails(tx *sqlx.Tx, fn func(*Email), criteria string, args ...interface{}) {
+	var (
+		e    Email
+		rows *sqlx.Rows
+		err  error
+	)
+	if criteria != "" {
+		rows, err = tx.Queryx(fmt.Sprintf(`SELECT * FROM email WHERE %s ORDER BY id`, criteria), args...)
+	} else {
+		rows, err = tx.Queryx(`SELECT * FROM email ORDER BY id`)
+	}
+	if err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		if err = rows.StructScan(&e); err != nil {
+			panic(err)
+		}
+		fn(&e)
+	}
+	if err = rows.Err(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EmailTemplate represents a staff-edited version of one of the named email
+// templates.  See db/schema.sql for details.
+type EmailTemplate struct {
+	Name    string `json:"name" db:"name"`
+	Subject string `json:"subject" db:"subject"`
+	Text    string `json:"text" db:"text"`
+	HTML    string `json:"html" db:"html"`
+}
+
+// Save saves an email template to the database.  Email templates are not part
+// of the JSON journal.
+func (t *EmailTemplate) Save(tx *sqlx.Tx) {
+	tx.MustExec(`INSERT OR REPLACE INTO emailTemplate (name, subject, text, html) VALUES (?,?,?,?)`,
+		t.Name, t.Subject, t.Text, t.HTML)
+}
+
+// Delete deletes an email template, reverting it to its default.
+func (t *EmailTemplate) Delete(tx *sqlx.Tx) {
+	tx.MustExec(`DELETE FROM emailTemplate WHERE name=?`, t.Name)
+}
+
+// FetchEmailTemplate returns the email template with the specified name.  It
+// returns nil if the template has not been edited by staff.
+func FetchEmailTemplate(tx *sqlx.Tx, name string) (t *EmailTemplate) {
+	t = new(EmailTemplate)
+	switch err := tx.Get(t, `SELECT * FROM emailTemplate WHERE name=?`, name); err {
+	case nil:
+		return t
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
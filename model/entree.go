@@ -0,0 +1,123 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+)
+
+// Entree represents an entree on the dinner menu.  See db/schema.sql for
+// details.
+type Entree struct {
+	ID     db.ID  `json:"id" db:"id"`
+	Code   string `json:"code" db:"code"`
+	Name   string `json:"name" db:"name"`
+	Label  string `json:"label" db:"label"`
+	Active bool   `json:"active" db:"active"`
+}
+
+// Save saves an entree to the database.  It also adds the entree to the JSON
+// journal.
+func (e *Entree) Save(tx *sqlx.Tx, je *JournalEntry) {
+	var (
+		res sql.Result
+		nid int64
+		err error
+	)
+	res, err = tx.Exec(`INSERT OR REPLACE INTO entree (id, code, name, label, active) VALUES (?,?,?,?,?)`,
+		e.ID, e.Code, e.Name, e.Label, e.Active)
+	if err != nil {
+		panic(err)
+	}
+	if e.ID == 0 {
+		if nid, err = res.LastInsertId(); err != nil {
+			panic(err)
+		} else {
+			e.ID = db.ID(nid)
+		}
+	}
+	je.MarkEntree(e.ID)
+}
+
+// Delete deletes an entree.  It also adds the deletion to the JSON journal.
+func (e *Entree) Delete(tx *sqlx.Tx, je *JournalEntry) {
+	tx.MustExec(`DELETE FROM entree WHERE id=?`, e.ID)
+	je.MarkEntree(e.ID)
+}
+
+// FetchEntree returns the entree with the specified ID.  It returns nil if the
+// entree does not exist.
+func FetchEntree(tx *sqlx.Tx, id db.ID) (e *Entree) {
+	e = new(Entree)
+	switch err := tx.Get(e, `SELECT * FROM entree WHERE id=?`, id); err {
+	case nil:
+		return e
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// FetchEntreeByCode returns the entree with the specified code.  It returns nil
+// if the entree does not exist.
+func FetchEntreeByCode(tx *sqlx.Tx, code string) (e *Entree) {
+	e = new(Entree)
+	switch err := tx.Get(e, `SELECT * FROM entree WHERE code=?`, code); err {
+	case nil:
+		return e
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// FetchEntrees calls the supplied function with each entree that matches the
+// supplied criteria.  The entrees are retrieved in no particular order.
+func FetchEntrees(tx *sqlx.Tx, fn func(*Entree), criteria string, args ...interface{}) {
+	var (
+		e    Entree
+		rows *sqlx.Rows
+		err  error
+	)
+	if criteria != "" {
+		rows, err = tx.Queryx(`SELECT * FROM entree WHERE `+criteria, args...)
+	} else {
+		rows, err = tx.Queryx(`SELECT * FROM entree`)
+	}
+	if err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		if err = rows.StructScan(&e); err != nil {
+			panic(err)
+		}
+		fn(&e)
+	}
+	if err = rows.Err(); err != nil {
+		panic(err)
+	}
+}
+
+// EntreeName returns the display name of the entree with the specified code.
+// If there is no such entree, it returns the code itself.
+func EntreeName(tx *sqlx.Tx, code string) string {
+	if e := FetchEntreeByCode(tx, code); e != nil {
+		return e.Name
+	}
+	return code
+}
+
+// ValidEntreeChoice returns whether code is an acceptable entree choice for a
+// guest whose current choice is old: it must be empty, unchanged, or the code
+// of an active entree.
+func ValidEntreeChoice(tx *sqlx.Tx, code, old string) bool {
+	if code == "" || code == old {
+		return true
+	}
+	e := FetchEntreeByCode(tx, code)
+	return e != nil && e.Active
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"database/sql"
+	"regexp"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 
@@ -28,7 +30,15 @@ type Guest struct {
 	UseCard            bool    `json:"useCard" db:"useCard"`
 	PayerID            db.ID   `json:"payer" db:"payer"`
 	Entree             string  `json:"entree" db:"entree"`
+	Allergies          string  `json:"allergies" db:"allergies"`
+	GlutenFree         bool    `json:"glutenFree" db:"glutenFree"`
+	Vegetarian         bool    `json:"vegetarian" db:"vegetarian"`
+	Wheelchair         bool    `json:"wheelchair" db:"wheelchair"`
+	HearingAssist      bool    `json:"hearingAssist" db:"hearingAssist"`
 	Notes              string  `json:"notes" db:"notes"`
+	Cancelled          bool    `json:"cancelled" db:"cancelled"`
+	Seat               int     `json:"seat" db:"seat"`
+	Ribbon             string  `json:"ribbon" db:"ribbon"`
 	PayingFor          []db.ID `json:"payingFor" db:"-"`
 	Purchases          []db.ID `json:"purchases" db:"-"`
 	PayingForPurchases []db.ID `json:"payingForPurchases" db:"-"`
@@ -39,19 +49,26 @@ type Guest struct {
 // journal.
 func (g *Guest) Save(tx *sqlx.Tx, je *JournalEntry) {
 	var (
-		res     sql.Result
-		obidder int
-		opayer  db.ID
-		oparty  db.ID
-		nid     int64
-		err     error
+		res        sql.Result
+		obidder    int
+		opayer     db.ID
+		oparty     db.ID
+		otable     db.ID // if the guest changed parties
+		ocancelled bool
+		nid        int64
+		err        error
 	)
 	if g.ID != 0 {
-		err = tx.QueryRow(`SELECT bidder, payer, party FROM guest WHERE id=?`, g.ID).Scan(&obidder, &opayer, &oparty)
+		err = tx.QueryRow(`SELECT bidder, payer, party, cancelled FROM guest WHERE id=?`, g.ID).
+			Scan(&obidder, &opayer, &oparty, &ocancelled)
 		if err != nil {
 			panic(err)
 		}
 	}
+	if g.PartyID != oparty || g.Cancelled {
+		// A guest's seat belongs to their place with their party.
+		g.Seat = 0
+	}
 	if g.PartyID == 0 {
 		var party Party
 		party.Save(tx, je)
@@ -59,9 +76,11 @@ func (g *Guest) Save(tx *sqlx.Tx, je *JournalEntry) {
 	}
 	res, err = tx.Exec(`
 INSERT OR REPLACE INTO guest (id, name, sortname, email, address, city, state, zip, phone, requests, party, bidder, stripeCustomer,
-    stripeSource, stripeDescription, useCard, payer, entree, notes) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+    stripeSource, stripeDescription, useCard, payer, entree, allergies, glutenFree, vegetarian, wheelchair, hearingAssist,
+    notes, cancelled, seat, ribbon) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
 		g.ID, g.Name, g.Sortname, g.Email, g.Address, g.City, g.State, g.Zip, g.Phone, g.Requests, g.PartyID, g.Bidder,
-		g.StripeCustomer, g.StripeSource, g.StripeDescription, g.UseCard, g.PayerID, g.Entree, g.Notes)
+		g.StripeCustomer, g.StripeSource, g.StripeDescription, g.UseCard, g.PayerID, g.Entree, g.Allergies, g.GlutenFree,
+		g.Vegetarian, g.Wheelchair, g.HearingAssist, g.Notes, g.Cancelled, g.Seat, g.Ribbon)
 	if err != nil {
 		panic(err)
 	}
@@ -84,6 +103,9 @@ INSERT OR REPLACE INTO guest (id, name, sortname, email, address, city, state, z
 		updateBidderNumbers(tx, je)
 	}
 	if oparty != 0 && oparty != g.PartyID {
+		// The old table's seated count changes, if it survives.
+		otable = FetchParty(tx, oparty).TableID
+		je.MarkTable(otable)
 		FetchParty(tx, oparty).deleteIfEmpty(tx, je)
 		je.MarkParty(oparty)
 	}
@@ -91,6 +113,14 @@ INSERT OR REPLACE INTO guest (id, name, sortname, email, address, city, state, z
 		je.MarkParty(g.PartyID)
 		updateBidderNumbers(tx, je)
 	}
+	if oparty != g.PartyID || ocancelled != g.Cancelled {
+		table := FetchParty(tx, g.PartyID).TableID
+		je.MarkTable(table)
+		updateSeats(tx, je, otable, table)
+		if err = tx.QueryRow(`SELECT seat FROM guest WHERE id=?`, g.ID).Scan(&g.Seat); err != nil {
+			panic(err)
+		}
+	}
 }
 
 // Populate adds computed data to the guest entry prior to its inclusion in a
@@ -114,6 +144,74 @@ func (g *Guest) Populate(tx *sqlx.Tx) {
 	}, `payer=?`, g.ID)
 }
 
+// placeholderNameRE matches the names given to guests whose names weren't
+// known at registration time, e.g. "Steve Roth Guest 1" or "Steve Roth Guest
+// #1".
+var placeholderNameRE = regexp.MustCompile(` Guest #?\d+$`)
+
+// HasPlaceholderName returns whether the guest's name is a placeholder
+// assigned at registration time, rather than the guest's real name.
+func (g *Guest) HasPlaceholderName() bool {
+	return placeholderNameRE.MatchString(g.Name)
+}
+
+// MissingInfo returns short descriptions of the information still needed
+// from the guest ("name", "entree choice"), or nil if nothing is missing.
+func (g *Guest) MissingInfo() (missing []string) {
+	if g.HasPlaceholderName() {
+		missing = append(missing, "name")
+	}
+	if g.Entree == "" {
+		missing = append(missing, "entree choice")
+	}
+	return missing
+}
+
+// FirstName returns the guest's first name, as best it can be determined from
+// their sort name: e.g. "Ann" for "Lee, Dr. Ann Marie".  Leading titles and
+// initials are skipped.  Guests with only one name get that name.
+func (g *Guest) FirstName() string {
+	var given []string
+
+	if _, after, ok := strings.Cut(g.Sortname, ", "); ok {
+		given = strings.Fields(strings.ReplaceAll(after, ",", " "))
+	}
+	for len(given) > 1 && strings.HasSuffix(given[0], ".") {
+		given = given[1:]
+	}
+	if len(given) == 0 {
+		return g.Name
+	}
+	return given[0]
+}
+
+// DietaryNeeds returns short descriptions of the guest's dietary
+// restrictions, suitable for printing, or nil if they have none.
+func (g *Guest) DietaryNeeds() (needs []string) {
+	if g.GlutenFree {
+		needs = append(needs, "Gluten free")
+	}
+	if g.Vegetarian {
+		needs = append(needs, "Vegetarian")
+	}
+	if g.Allergies != "" {
+		needs = append(needs, "Allergies: "+g.Allergies)
+	}
+	return needs
+}
+
+// AccessNeeds returns short descriptions of the guest's accessibility needs,
+// suitable for printing, or nil if they have none.
+func (g *Guest) AccessNeeds() (needs []string) {
+	if g.Wheelchair {
+		needs = append(needs, "Wheelchair access")
+	}
+	if g.HearingAssist {
+		needs = append(needs, "Hearing assistance")
+	}
+	return needs
+}
+
 // Delete deletes a guest.  It also adds the deletion to the JSON journal.
 func (g *Guest) Delete(tx *sqlx.Tx, je *JournalEntry) {
 	tx.MustExec(`DELETE FROM guest WHERE id=?`, g.ID)
@@ -124,6 +222,7 @@ func (g *Guest) Delete(tx *sqlx.Tx, je *JournalEntry) {
 	if g.Bidder != 0 {
 		je.MarkBidderToGuest()
 	}
+	je.MarkTable(FetchParty(tx, g.PartyID).TableID)
 	FetchParty(tx, g.PartyID).deleteIfEmpty(tx, je)
 	je.MarkParty(g.PartyID)
 }
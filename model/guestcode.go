@@ -0,0 +1,67 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/db"
+)
+
+// guestCodeRE matches a guest code, possibly at the end of a URL.  The code is
+// the guest ID and a signature, in upper case so that a QR code can encode it
+// compactly.
+var guestCodeRE = regexp.MustCompile(`G(\d+)-([A-Z2-7]{16})$`)
+
+// guestCodeSignature returns the signature for a guest code, or an empty
+// string if no qrSecret is set in config.json.
+func guestCodeSignature(id db.ID) string {
+	var secret = config.Get("qrSecret")
+
+	if secret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "guest %d", id)
+	return base32.StdEncoding.EncodeToString(mac.Sum(nil)[:10])
+}
+
+// Code returns the signed code that identifies the guest, for printing on
+// check-in forms, badges, and paddles as a QR code.  If the qrURL setting is
+// set in config.json, the code is appended to it, so that scanning it with a
+// phone camera opens the check-in page for the guest.  Code returns an empty
+// string if no qrSecret is set in config.json.
+func (g *Guest) Code() string {
+	var sig = guestCodeSignature(g.ID)
+
+	if sig == "" {
+		return ""
+	}
+	return fmt.Sprintf("%sG%d-%s", config.Get("qrURL"), g.ID, sig)
+}
+
+// FetchGuestByCode returns the guest identified by a code returned by Code
+// (with or without the URL).  It returns nil if the code is not valid or the
+// guest no longer exists.
+func FetchGuestByCode(tx *sqlx.Tx, code string) *Guest {
+	var match = guestCodeRE.FindStringSubmatch(code)
+
+	if match == nil {
+		return nil
+	}
+	id, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil
+	}
+	sig := guestCodeSignature(db.ID(id))
+	if sig == "" || !hmac.Equal([]byte(sig), []byte(match[2])) {
+		return nil
+	}
+	return FetchGuest(tx, db.ID(id))
+}
@@ -8,14 +8,59 @@ import (
 	"github.com/scholacantorum/gala-backend/db"
 )
 
+// Item type values.
+const (
+	ItemRegistration = "registration"
+	ItemSilent       = "silent"
+	ItemLive         = "live"
+	ItemFundANeed    = "fundANeed"
+	ItemDonation     = "donation"
+	ItemRaffle       = "raffle"
+)
+
+// ItemTypes lists the valid item types.
+var ItemTypes = []string{ItemRegistration, ItemSilent, ItemLive, ItemFundANeed, ItemDonation, ItemRaffle}
+
 // Item represents a item that can be purchased, or a donation level.  See
 // db/schema.sql for details.
 type Item struct {
-	ID        db.ID   `json:"id" db:"id"`
-	Name      string  `json:"name" db:"name"`
-	Amount    int     `json:"amount" db:"amount"`
-	Value     int     `json:"value" db:"value"`
-	Purchases []db.ID `json:"purchases" db:"-"`
+	ID           db.ID   `json:"id" db:"id"`
+	Type         string  `json:"type" db:"type"`
+	Lot          string  `json:"lot" db:"lot"`
+	Name         string  `json:"name" db:"name"`
+	Description  string  `json:"description" db:"description"`
+	Donor        string  `json:"donor" db:"donor"`
+	Restrictions string  `json:"restrictions" db:"restrictions"`
+	Amount       int     `json:"amount" db:"amount"`
+	Value        int     `json:"value" db:"value"`
+	Purchases    []db.ID `json:"purchases" db:"-"`
+}
+
+// ValidItemType returns whether the specified item type is valid.
+func ValidItemType(t string) bool {
+	for _, it := range ItemTypes {
+		if t == it {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAuction returns whether the item is sold at auction (silent or live).
+func (i *Item) IsAuction() bool {
+	return i.Type == ItemSilent || i.Type == ItemLive
+}
+
+// IsDonation returns whether the item is purely a donation (a fund-a-need
+// level or other donation), for which the purchaser receives nothing.
+func (i *Item) IsDonation() bool {
+	return i.Type == ItemFundANeed || i.Type == ItemDonation
+}
+
+// NeedsPickup returns whether purchases of the item need to be picked up.
+// Registrations, donations, and raffle tickets don't.
+func (i *Item) NeedsPickup() bool {
+	return i.IsAuction()
 }
 
 // Save saves an item to the database.  It also adds the item to the JSON
@@ -26,8 +71,10 @@ func (i *Item) Save(tx *sqlx.Tx, je *JournalEntry) {
 		nid int64
 		err error
 	)
-	res, err = tx.Exec(`INSERT OR REPLACE INTO item (id, name, amount, value) VALUES (?,?,?,?)`,
-		i.ID, i.Name, i.Amount, i.Value)
+	res, err = tx.Exec(`
+INSERT OR REPLACE INTO item (id, type, lot, name, description, donor, restrictions, amount, value)
+    VALUES (?,?,?,?,?,?,?,?,?)`,
+		i.ID, i.Type, i.Lot, i.Name, i.Description, i.Donor, i.Restrictions, i.Amount, i.Value)
 	if err != nil {
 		panic(err)
 	}
@@ -70,6 +117,35 @@ func FetchItem(tx *sqlx.Tx, id db.ID) (i *Item) {
 	}
 }
 
+// FetchRegistrationItem returns the item for a standard gala registration:
+// the registration item with the lowest ID.  It returns nil if there are no
+// registration items.
+func FetchRegistrationItem(tx *sqlx.Tx) (i *Item) {
+	i = new(Item)
+	switch err := tx.Get(i, `SELECT * FROM item WHERE type=? ORDER BY id LIMIT 1`, ItemRegistration); err {
+	case nil:
+		return i
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// FetchItemByLot returns the item with the specified lot number.  It returns
+// nil if there is no such item.
+func FetchItemByLot(tx *sqlx.Tx, lot string) (i *Item) {
+	i = new(Item)
+	switch err := tx.Get(i, `SELECT * FROM item WHERE lot=?`, lot); err {
+	case nil:
+		return i
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
 // FetchItems calls the supplied function with each item that matches the
 // supplied criteria.  The items are retrieved in no particular order.
 func FetchItems(tx *sqlx.Tx, fn func(*Item), criteria string, args ...interface{}) {
@@ -11,9 +11,15 @@ type JournalEntry struct {
 	Tables        map[db.ID]*Table    `json:"tables,omitempty"`
 	Parties       map[db.ID]*Party    `json:"parties,omitempty"`
 	Guests        map[db.ID]*Guest    `json:"guests,omitempty"`
+	Entrees       map[db.ID]*Entree   `json:"entrees,omitempty"`
 	Items         map[db.ID]*Item     `json:"items,omitempty"`
 	Purchases     map[db.ID]*Purchase `json:"purchases,omitempty"`
 	BidderToGuest map[int]db.ID       `json:"bidderToGuest,omitempty"`
+
+	// BidderNumbering is the name of the bidder numbering scheme in use
+	// (see FormatBidder), so that clients can format and parse bidder
+	// numbers the same way.  It is only sent in the full data set.
+	BidderNumbering string `json:"bidderNumbering,omitempty"`
 }
 
 // MarkTable marks a table as having been changed or deleted.
@@ -40,6 +46,14 @@ func (j *JournalEntry) MarkGuest(id db.ID) {
 	j.Guests[id] = nil
 }
 
+// MarkEntree marks an entree as having been changed or deleted.
+func (j *JournalEntry) MarkEntree(id db.ID) {
+	if j.Entrees == nil {
+		j.Entrees = make(map[db.ID]*Entree)
+	}
+	j.Entrees[id] = nil
+}
+
 // MarkItem marks an item as having been changed or deleted.
 func (j *JournalEntry) MarkItem(id db.ID) {
 	if j.Items == nil {
@@ -78,6 +92,9 @@ func (j *JournalEntry) Populate(tx *sqlx.Tx) {
 			j.Guests[gid].Populate(tx)
 		}
 	}
+	for eid := range j.Entrees {
+		j.Entrees[eid] = FetchEntree(tx, eid)
+	}
 	for iid := range j.Items {
 		if j.Items[iid] = FetchItem(tx, iid); j.Items[iid] != nil {
 			j.Items[iid].Populate(tx)
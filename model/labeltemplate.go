@@ -0,0 +1,115 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LabelTemplate describes a sheet of labels: its page size, the layout of the
+// labels on it, and the text printed on each label.  All measurements are in
+// points.  See db/schema.sql for details.
+type LabelTemplate struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	PageWidth   float64 `json:"pageWidth"`
+	PageHeight  float64 `json:"pageHeight"`
+	Rows        int     `json:"rows"`
+	Columns     int     `json:"columns"`
+	Top         float64 `json:"top"`       // top margin, to the first row
+	Left        float64 `json:"left"`      // left margin, to the first column
+	Width       float64 `json:"width"`     // of each label
+	Height      float64 `json:"height"`    // of each label
+	ColumnGap   float64 `json:"columnGap"` // horizontal gutter between labels
+	RowGap      float64 `json:"rowGap"`    // vertical gutter between labels
+	PaddingX    float64 `json:"paddingX"`  // inside the left and right edges of each label
+	PaddingY    float64 `json:"paddingY"`  // inside the top and bottom edges of each label
+	FillDown    bool    `json:"fillDown"`  // fill each column before the next, rather than each row
+	Center      bool    `json:"center"`    // center the text vertically on each label
+	HashMarks   bool    `json:"hashMarks"` // print cutting guides in the page margins
+	// Fields are the lines of text printed on each label.  If there are
+	// none, the lines chosen by the kind of label being printed are used.
+	Fields []LabelField `json:"fields"`
+}
+
+// LabelField is a line of text printed on a label.
+type LabelField struct {
+	// Text is a Go text/template executed against a map of the values
+	// available for the kind of label being printed (e.g. {{.name}}).
+	// Lines that come out empty are omitted.
+	Text string `json:"text"`
+	// Size is the font size.  The font is reduced as needed for the text to
+	// fit the width of the label (in at most Lines lines).
+	Size float64 `json:"size"`
+	Bold bool    `json:"bold"`
+	// Align is "L", "C", or "R"; the default is "L".
+	Align string `json:"align"`
+	// Height is the height of the line; the default is 1.2 times Size.
+	Height float64 `json:"height"`
+	// Lines is the number of lines the text may be wrapped onto; the
+	// default is 1.
+	Lines int `json:"lines"`
+	// Inline lines are printed on the same line as the one before them,
+	// rather than below it.
+	Inline bool `json:"inline"`
+	// Banner lines are printed in white on a black band across the full
+	// width of the label, e.g. for a ribbon on a name badge.
+	Banner bool `json:"banner"`
+	// QR lines are printed as a QR code encoding the text, Size points
+	// square, rather than as text.
+	QR bool `json:"qr"`
+}
+
+// Save saves a label template to the database.  Label templates are not part
+// of the JSON journal.
+func (t *LabelTemplate) Save(tx *sqlx.Tx) {
+	var (
+		spec []byte
+		err  error
+	)
+	if spec, err = json.Marshal(t); err != nil {
+		panic(err)
+	}
+	tx.MustExec(`INSERT OR REPLACE INTO labelTemplate (name, spec) VALUES (?,?)`, t.Name, spec)
+}
+
+// Delete deletes a label template, reverting it to its default if it is one of
+// the built-in templates.
+func (t *LabelTemplate) Delete(tx *sqlx.Tx) {
+	tx.MustExec(`DELETE FROM labelTemplate WHERE name=?`, t.Name)
+}
+
+// FetchLabelTemplate returns the label template with the specified name.  It
+// returns nil if no such template has been saved by staff.
+func FetchLabelTemplate(tx *sqlx.Tx, name string) (t *LabelTemplate) {
+	var spec string
+
+	switch err := tx.QueryRow(`SELECT spec FROM labelTemplate WHERE name=?`, name).Scan(&spec); err {
+	case nil:
+		break
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+	t = new(LabelTemplate)
+	if err := json.Unmarshal([]byte(spec), t); err != nil {
+		panic(err)
+	}
+	t.Name = name
+	return t
+}
+
+// FetchLabelTemplates calls the supplied function with each label template
+// saved by staff, in name order.
+func FetchLabelTemplates(tx *sqlx.Tx, fn func(*LabelTemplate)) {
+	var names []string
+
+	if err := tx.Select(&names, `SELECT name FROM labelTemplate ORDER BY name`); err != nil {
+		panic(err)
+	}
+	for _, name := range names {
+		fn(FetchLabelTemplate(tx, name))
+	}
+}
@@ -11,10 +11,12 @@ import (
 // Party represents a party that should be seated together.  See db/schema.sql
 // for details.
 type Party struct {
-	ID      db.ID   `json:"id" db:"id"`
-	TableID db.ID   `json:"table" db:"gtable"`
-	Place   int     `json:"place" db:"place"`
-	Guests  []db.ID `json:"guests" db:"-"`
+	ID       db.ID   `json:"id" db:"id"`
+	TableID  db.ID   `json:"table" db:"gtable"`
+	Place    int     `json:"place" db:"place"`
+	Guests   []db.ID `json:"guests" db:"-"`
+	SeatWith []db.ID `json:"seatWith" db:"-"`
+	NotNear  []db.ID `json:"notNear" db:"-"`
 }
 
 // Save saves a party to the database.  It also adds the party to the JSON
@@ -59,7 +61,14 @@ func (p *Party) Save(tx *sqlx.Tx, je *JournalEntry) {
 	}
 	if otableID != p.TableID {
 		je.MarkTable(p.TableID)
+		FetchGuestsInParty(tx, p.ID, func(g *Guest) {
+			if g.Seat != 0 {
+				g.Seat = 0
+				g.Save(tx, je)
+			}
+		})
 		updateBidderNumbers(tx, je)
+		updateSeats(tx, je, otableID, p.TableID)
 	}
 }
 
@@ -70,10 +79,77 @@ func (p *Party) Populate(tx *sqlx.Tx) {
 	FetchGuestsInParty(tx, p.ID, func(g *Guest) {
 		p.Guests = append(p.Guests, g.ID)
 	})
+	p.SeatWith, p.NotNear = FetchPartyConstraints(tx, p.ID)
+}
+
+// SaveConstraints replaces the seating constraints involving the party with
+// those in its SeatWith and NotNear lists.  (Party.Save doesn't do this, since
+// most callers don't load the lists.)  It also adds all parties whose
+// constraints changed to the JSON journal.
+func (p *Party) SaveConstraints(tx *sqlx.Tx, je *JournalEntry) {
+	var oseatWith, onotNear = FetchPartyConstraints(tx, p.ID)
+
+	for _, id := range append(oseatWith, onotNear...) {
+		je.MarkParty(id)
+	}
+	tx.MustExec(`DELETE FROM partyConstraint WHERE party=? OR other=?`, p.ID, p.ID)
+	for _, id := range p.SeatWith {
+		savePartyConstraint(tx, p.ID, id, "seatWith")
+		je.MarkParty(id)
+	}
+	for _, id := range p.NotNear {
+		savePartyConstraint(tx, p.ID, id, "notNear")
+		je.MarkParty(id)
+	}
+	je.MarkParty(p.ID)
+}
+
+func savePartyConstraint(tx *sqlx.Tx, party, other db.ID, kind string) {
+	if party > other {
+		party, other = other, party
+	}
+	tx.MustExec(`INSERT OR REPLACE INTO partyConstraint (party, other, kind) VALUES (?,?,?)`, party, other, kind)
+}
+
+// FetchPartyConstraints returns the IDs of the parties that the specified party
+// should be seated with, and those it must not be seated near.
+func FetchPartyConstraints(tx *sqlx.Tx, id db.ID) (seatWith, notNear []db.ID) {
+	var (
+		other db.ID
+		kind  string
+		rows  *sqlx.Rows
+		err   error
+	)
+	seatWith, notNear = []db.ID{}, []db.ID{}
+	rows, err = tx.Queryx(`
+SELECT other, kind FROM partyConstraint WHERE party=?1 UNION SELECT party, kind FROM partyConstraint WHERE other=?1`, id)
+	if err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		if err = rows.Scan(&other, &kind); err != nil {
+			panic(err)
+		}
+		if kind == "seatWith" {
+			seatWith = append(seatWith, other)
+		} else {
+			notNear = append(notNear, other)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		panic(err)
+	}
+	return seatWith, notNear
 }
 
 // Delete deletes a party.  It also adds the deletion to the JSON journal.
 func (p *Party) Delete(tx *sqlx.Tx, je *JournalEntry) {
+	var seatWith, notNear = FetchPartyConstraints(tx, p.ID)
+
+	for _, id := range append(seatWith, notNear...) {
+		je.MarkParty(id)
+	}
+	tx.MustExec(`DELETE FROM partyConstraint WHERE party=? OR other=?`, p.ID, p.ID)
 	tx.MustExec(`DELETE FROM party WHERE id=?`, p.ID)
 	je.MarkParty(p.ID)
 	FetchTable(tx, p.TableID).deleteIfEmpty(tx, je)
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/db"
+)
+
+// PortalToken returns the guest portal token for the specified host, issuing
+// one if they don't have one yet.
+func PortalToken(tx *sqlx.Tx, host db.ID) (token string) {
+	var (
+		tokenb [24]byte
+		err    error
+	)
+	switch err = tx.QueryRow(`SELECT token FROM portalToken WHERE guest=?`, host).Scan(&token); err {
+	case nil:
+		return token
+	case sql.ErrNoRows:
+		break
+	default:
+		panic(err)
+	}
+	if _, err = rand.Read(tokenb[:]); err != nil {
+		panic(err)
+	}
+	token = base64.URLEncoding.EncodeToString(tokenb[:])
+	tx.MustExec(`INSERT INTO portalToken (token, guest, created) VALUES (?,?,?)`,
+		token, host, db.Time{Time: time.Now()})
+	return token
+}
+
+// PortalLink returns the link to the guest portal for the specified host,
+// issuing a token if they don't have one yet.  It returns an empty string if
+// no portalURL is set in config.json.
+func PortalLink(tx *sqlx.Tx, host db.ID) string {
+	var url = config.Get("portalURL")
+
+	if url == "" {
+		return ""
+	}
+	return url + PortalToken(tx, host)
+}
+
+// RevokePortalToken revokes the guest portal token for the specified host, if
+// they have one.  A new one will be issued the next time it's needed.
+func RevokePortalToken(tx *sqlx.Tx, host db.ID) {
+	tx.MustExec(`DELETE FROM portalToken WHERE guest=?`, host)
+}
+
+// FetchGuestByPortalToken returns the host to whom the specified guest portal
+// token was issued.  It returns nil if the token is not valid.
+func FetchGuestByPortalToken(tx *sqlx.Tx, token string) *Guest {
+	var (
+		id  db.ID
+		err error
+	)
+	switch err = tx.QueryRow(`SELECT guest FROM portalToken WHERE token=?`, token).Scan(&id); err {
+	case nil:
+		return FetchGuest(tx, id)
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
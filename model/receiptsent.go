@@ -0,0 +1,39 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+)
+
+// ReceiptSent records the version of a payer's year-end receipt that was
+// emailed to them.  See db/schema.sql for details.
+type ReceiptSent struct {
+	PayerID db.ID   `json:"payer" db:"payer"`
+	Hash    string  `json:"hash" db:"hash"`
+	EmailID db.ID   `json:"email" db:"email"`
+	Sent    db.Time `json:"sent" db:"sent"`
+}
+
+// Save saves a receipt sent record to the database.  These records are not
+// part of the JSON journal.
+func (rs *ReceiptSent) Save(tx *sqlx.Tx) {
+	tx.MustExec(`INSERT OR REPLACE INTO receiptSent (payer, hash, email, sent) VALUES (?,?,?,?)`,
+		rs.PayerID, rs.Hash, rs.EmailID, rs.Sent)
+}
+
+// FetchReceiptSent returns the receipt sent record for the specified payer.
+// It returns nil if no receipt has been sent to that payer.
+func FetchReceiptSent(tx *sqlx.Tx, payer db.ID) (rs *ReceiptSent) {
+	rs = new(ReceiptSent)
+	switch err := tx.Get(rs, `SELECT * FROM receiptSent WHERE payer=?`, payer); err {
+	case nil:
+		return rs
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+)
+
+// ReminderSent records the reminders to supply missing guest information that
+// were emailed to a host.  See db/schema.sql for details.
+type ReminderSent struct {
+	HostID  db.ID   `json:"host" db:"host"`
+	Count   int     `json:"count" db:"count"`
+	EmailID db.ID   `json:"email" db:"email"`
+	Sent    db.Time `json:"sent" db:"sent"`
+}
+
+// Save saves a reminder sent record to the database.  These records are not
+// part of the JSON journal.
+func (rs *ReminderSent) Save(tx *sqlx.Tx) {
+	tx.MustExec(`INSERT OR REPLACE INTO reminderSent (host, count, email, sent) VALUES (?,?,?,?)`,
+		rs.HostID, rs.Count, rs.EmailID, rs.Sent)
+}
+
+// FetchReminderSent returns the reminder sent record for the specified host.
+// It returns nil if no reminder has been sent to that host.
+func FetchReminderSent(tx *sqlx.Tx, host db.ID) (rs *ReminderSent) {
+	rs = new(ReminderSent)
+	switch err := tx.Get(rs, `SELECT * FROM reminderSent WHERE host=?`, host); err {
+	case nil:
+		return rs
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+)
+
+// updateSeats ensures that every attending guest at the specified tables has a
+// seat if the table is numbered, and that no one else at them does.  Guests who
+// already have valid seats keep them.  Tables that no longer exist are
+// ignored.
+func updateSeats(tx *sqlx.Tx, je *JournalEntry, tableIDs ...db.ID) {
+	var done = make(map[db.ID]bool)
+
+	for _, id := range tableIDs {
+		if done[id] {
+			continue
+		}
+		done[id] = true
+		if table := FetchTable(tx, id); table != nil {
+			assignSeatsAtTable(tx, je, table, false)
+		}
+	}
+}
+
+// AssignSeats gives seats to the attending guests at the table who don't have
+// them, keeping everyone else's seats.  It also adds the guests whose seats
+// changed to the JSON journal.
+func AssignSeats(tx *sqlx.Tx, je *JournalEntry, table *Table) {
+	assignSeatsAtTable(tx, je, table, false)
+}
+
+// ReassignSeats discards all of the seat assignments at the table and assigns
+// them afresh, in party order, so that every party sits together.  It also
+// adds the guests whose seats changed to the JSON journal.
+func ReassignSeats(tx *sqlx.Tx, je *JournalEntry, table *Table) {
+	assignSeatsAtTable(tx, je, table, true)
+}
+
+// assignSeatsAtTable assigns seats to the guests at a table who don't have
+// valid ones (or to all of them, if reset is true).  Each party's guests are
+// given adjacent seats if possible, following the party's other guests if any
+// of them already have seats.
+func assignSeatsAtTable(tx *sqlx.Tx, je *JournalEntry, table *Table, reset bool) {
+	var (
+		parties [][]*Guest
+		taken   = make(map[int]bool)
+		seated  int
+		nseats  int
+	)
+	FetchPartiesAtTable(tx, table.ID, func(p *Party) {
+		var guests []*Guest
+		FetchGuestsInParty(tx, p.ID, func(g *Guest) {
+			var copy = *g
+			guests = append(guests, &copy)
+		})
+		parties = append(parties, guests)
+	})
+	// Clear the seats that are invalid, noting the ones that remain taken.
+	for _, guests := range parties {
+		for _, g := range guests {
+			if g.Cancelled || table.Number == 0 || reset || g.Seat < 0 || taken[g.Seat] {
+				if g.Seat != 0 {
+					g.Seat = 0
+					g.Save(tx, je)
+				}
+			} else if g.Seat != 0 {
+				taken[g.Seat] = true
+			}
+			if !g.Cancelled {
+				seated++
+			}
+		}
+	}
+	if table.Number == 0 {
+		return
+	}
+	if nseats = table.EffectiveCapacity(); seated > nseats {
+		nseats = seated
+	}
+	// Assign seats to the guests who need them, a party at a time.
+	for _, guests := range parties {
+		var (
+			pending []*Guest
+			last    int
+			start   int
+		)
+		for _, g := range guests {
+			if g.Cancelled {
+				continue
+			}
+			if g.Seat == 0 {
+				pending = append(pending, g)
+			} else if g.Seat > last {
+				last = g.Seat
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		if last != 0 && freeRun(taken, last+1, len(pending), nseats) {
+			start = last + 1
+		} else {
+			for s := 1; s+len(pending)-1 <= nseats; s++ {
+				if freeRun(taken, s, len(pending), nseats) {
+					start = s
+					break
+				}
+			}
+		}
+		// If there's no run of adjacent seats long enough, the party
+		// will have to be split up; use the lowest free seats.
+		s := start
+		if s == 0 {
+			s = 1
+		}
+		for _, g := range pending {
+			for taken[s] {
+				s++
+			}
+			g.Seat = s
+			taken[s] = true
+			g.Save(tx, je)
+		}
+	}
+}
+
+// freeRun returns whether the count seats starting at start are all free and
+// within the first nseats.
+func freeRun(taken map[int]bool, start, count, nseats int) bool {
+	if start+count-1 > nseats {
+		return false
+	}
+	for s := start; s < start+count; s++ {
+		if taken[s] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,21 +2,31 @@ package model
 
 import (
 	"database/sql"
+	"fmt"
+	"strconv"
 
 	"github.com/jmoiron/sqlx"
 
+	"github.com/scholacantorum/gala-backend/config"
 	"github.com/scholacantorum/gala-backend/db"
 )
 
+// defaultTableCapacity is the table capacity used when config.json doesn't
+// specify one.
+const defaultTableCapacity = 10
+
 // Table represents a table, or potential table, at the event.  See
 // db/schema.sql for details.
 type Table struct {
-	ID      db.ID   `json:"id" db:"id"`
-	X       int     `json:"x" db:"x"`
-	Y       int     `json:"y" db:"y"`
-	Number  int     `json:"number" db:"num"`
-	Name    string  `json:"name" db:"name"`
-	Parties []db.ID `json:"parties" db:"-"`
+	ID       db.ID   `json:"id" db:"id"`
+	X        int     `json:"x" db:"x"`
+	Y        int     `json:"y" db:"y"`
+	Number   int     `json:"number" db:"num"`
+	Name     string  `json:"name" db:"name"`
+	Capacity int     `json:"capacity" db:"capacity"`
+	Parties  []db.ID `json:"parties" db:"-"`
+	Seated   int     `json:"seated" db:"-"`
+	Open     int     `json:"open" db:"-"`
 }
 
 // Save saves a table to the database.  It also adds the table to the JSON
@@ -33,7 +43,8 @@ func (t *Table) Save(tx *sqlx.Tx, je *JournalEntry) {
 			panic(err)
 		}
 	}
-	res, err = tx.Exec(`INSERT OR REPLACE INTO gtable (id, x, y, num, name) VALUES (?,?,?,?,?)`, t.ID, t.X, t.Y, t.Number, t.Name)
+	res, err = tx.Exec(`INSERT OR REPLACE INTO gtable (id, x, y, num, name, capacity) VALUES (?,?,?,?,?,?)`,
+		t.ID, t.X, t.Y, t.Number, t.Name, t.Capacity)
 	if err != nil {
 		panic(err)
 	}
@@ -47,6 +58,7 @@ func (t *Table) Save(tx *sqlx.Tx, je *JournalEntry) {
 	je.MarkTable(t.ID)
 	if t.Number != otnum {
 		updateBidderNumbers(tx, je)
+		updateSeats(tx, je, t.ID)
 	}
 }
 
@@ -57,13 +69,27 @@ func (t *Table) Populate(tx *sqlx.Tx) {
 	FetchPartiesAtTable(tx, t.ID, func(p *Party) {
 		t.Parties = append(t.Parties, p.ID)
 	})
+	t.Seated = SeatedAtTable(tx, t.ID)
+	t.Open = t.EffectiveCapacity() - t.Seated
+}
+
+// EffectiveCapacity returns the number of guests the table seats, applying the
+// configured default if the table doesn't have its own capacity.
+func (t *Table) EffectiveCapacity() int {
+	if t.Capacity != 0 {
+		return t.Capacity
+	}
+	if capacity, err := strconv.Atoi(config.Get("defaultTableCapacity")); err == nil && capacity > 0 {
+		return capacity
+	}
+	return defaultTableCapacity
 }
 
 // NextPlace returns the next unused place number at this table.
 func (t *Table) NextPlace(tx *sqlx.Tx) (place int) {
 	var err error
 
-	if err = tx.QueryRow(`SELECT MAX(place) FROM party WHERE gtable=?`, t.ID).Scan(&place); err != nil {
+	if err = tx.QueryRow(`SELECT COALESCE(MAX(place), 0) FROM party WHERE gtable=?`, t.ID).Scan(&place); err != nil {
 		panic(err)
 	}
 	return place + 1
@@ -132,6 +158,23 @@ func FetchTables(tx *sqlx.Tx, fn func(*Table), criteria string, args ...interfac
 	}
 }
 
+// RenumberTables changes the numbers of the tables identified in the map (keyed
+// by table ID) to the corresponding numbers.  Unlike saving each table in
+// turn, it recalculates bidder numbers only once, after all of the tables
+// have their new numbers.  It also adds the changed tables to the JSON
+// journal.
+func RenumberTables(tx *sqlx.Tx, je *JournalEntry, numbers map[db.ID]int) {
+	var ids []db.ID
+
+	for id, num := range numbers {
+		tx.MustExec(`UPDATE gtable SET num=? WHERE id=?`, num, id)
+		je.MarkTable(id)
+		ids = append(ids, id)
+	}
+	updateBidderNumbers(tx, je)
+	updateSeats(tx, je, ids...)
+}
+
 // NextTableNumber returns the next unused table number.
 func NextTableNumber(tx *sqlx.Tx) (number int) {
 	var err error
@@ -141,3 +184,68 @@ func NextTableNumber(tx *sqlx.Tx) (number int) {
 	}
 	return number + 1
 }
+
+// SeatedAtTable returns the number of guests seated at the table with the
+// specified ID.  Cancelled guests are not counted.
+func SeatedAtTable(tx *sqlx.Tx, id db.ID) (seated int) {
+	err := tx.QueryRow(`SELECT COUNT(*) FROM guest g, party p WHERE g.party=p.id AND p.gtable=? AND NOT g.cancelled`, id).
+		Scan(&seated)
+	if err != nil {
+		panic(err)
+	}
+	return seated
+}
+
+// SeatingCounts returns the number of guests seated at each table, keyed by
+// table ID.  Cancelled guests are not counted.  It is used to take a snapshot
+// before moving guests, for use with OverfillWarning.
+func SeatingCounts(tx *sqlx.Tx) (counts map[db.ID]int) {
+	var (
+		id    db.ID
+		count int
+		rows  *sqlx.Rows
+		err   error
+	)
+	counts = make(map[db.ID]int)
+	rows, err = tx.Queryx(`SELECT p.gtable, COUNT(*) FROM guest g, party p WHERE g.party=p.id AND NOT g.cancelled GROUP BY p.gtable`)
+	if err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		if err = rows.Scan(&id, &count); err != nil {
+			panic(err)
+		}
+		counts[id] = count
+	}
+	if err = rows.Err(); err != nil {
+		panic(err)
+	}
+	return counts
+}
+
+// OverfillWarning returns a warning message if the table with the specified ID
+// has more guests seated than its capacity, and more than it had when the
+// before snapshot (from SeatingCounts) was taken.  Otherwise it returns an
+// empty string.  Tables without numbers are not checked, since they are only
+// holding areas for parties that haven't been seated yet.
+func OverfillWarning(tx *sqlx.Tx, before map[db.ID]int, id db.ID) string {
+	var (
+		table  *Table
+		seated int
+	)
+	if table = FetchTable(tx, id); table == nil || table.Number == 0 {
+		return ""
+	}
+	seated = SeatedAtTable(tx, id)
+	if seated <= table.EffectiveCapacity() || seated <= before[id] {
+		return ""
+	}
+	return fmt.Sprintf("Table %d now has %d guests, but seats only %d.", table.Number, seated, table.EffectiveCapacity())
+}
+
+// RefuseOverfill returns whether moves that over-fill a table should be
+// refused, rather than allowed with a warning.  This is controlled by the
+// overfillTables setting in config.json ("warn" or "refuse"; default "warn").
+func RefuseOverfill() bool {
+	return config.Get("overfillTables") == "refuse"
+}
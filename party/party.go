@@ -2,6 +2,7 @@ package party
 
 import (
 	"encoding/json"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -56,9 +57,11 @@ func saveParty(w *request.ResponseWriter, r *request.Request, party *model.Party
 		Y int `json:"y"`
 	}
 	var (
-		body savePartyBody
-		je   model.JournalEntry
-		err  error
+		body    savePartyBody
+		je      model.JournalEntry
+		before  map[db.ID]int
+		warning string
+		err     error
 	)
 	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
 		log.Printf("saveParty JSON decode %s", err)
@@ -75,7 +78,20 @@ func saveParty(w *request.ResponseWriter, r *request.Request, party *model.Party
 			return
 		}
 	}
+	if body.SeatWith != nil || body.NotNear != nil {
+		if !validConstraints(r, &body.Party) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body.SaveConstraints(r.Tx, &je)
+	}
+	before = model.SeatingCounts(r.Tx)
 	body.Save(r.Tx, &je)
+	if warning = model.OverfillWarning(r.Tx, before, body.TableID); warning != "" && model.RefuseOverfill() {
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, warning)
+		return
+	}
 	if body.X != 0 || body.Y != 0 {
 		table := model.FetchTable(r.Tx, body.TableID)
 		table.X = body.X
@@ -83,5 +99,29 @@ func saveParty(w *request.ResponseWriter, r *request.Request, party *model.Party
 		table.Save(r.Tx, &je)
 	}
 	journal.Log(r, &je)
-	w.CommitNoContent(r)
+	w.CommitWarning(r, warning)
+}
+
+// validConstraints verifies the seating constraints in a party being saved.
+// The other parties must exist, and no party can appear in both lists.  If
+// only one list was supplied, the other is filled in with the party's current
+// constraints.
+func validConstraints(r *request.Request, party *model.Party) bool {
+	var (
+		seen              = make(map[db.ID]bool)
+		seatWith, notNear = model.FetchPartyConstraints(r.Tx, party.ID)
+	)
+	if party.SeatWith == nil {
+		party.SeatWith = seatWith
+	}
+	if party.NotNear == nil {
+		party.NotNear = notNear
+	}
+	for _, id := range append(party.SeatWith, party.NotNear...) {
+		if id == party.ID || seen[id] || model.FetchParty(r.Tx, id) == nil {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
 }
@@ -40,12 +40,12 @@ func serveExportPurchases(w *request.ResponseWriter, r *request.Request) {
 				unpaid = "NOT FULLY PAID"
 			}
 			item = model.FetchItem(r.Tx, p.ItemID)
-			if item.ID == 1 { // registration
+			if item.Type == model.ItemRegistration {
 				regcount++
 				regtotal += p.Amount
 				return
 			}
-			if item.Value != 0 {
+			if !item.IsDonation() {
 				auctionItems = append(auctionItems, item.Name)
 				auctionPaid += p.Amount
 				auctionValue += item.Value
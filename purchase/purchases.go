@@ -43,7 +43,7 @@ func addPurchase(w *request.ResponseWriter, r *request.Request) {
 		je    model.JournalEntry
 		guest *model.Guest
 		payer *model.Guest
-		isFAN bool
+		item  *model.Item
 		isDup bool
 		err   error
 	)
@@ -60,11 +60,9 @@ func addPurchase(w *request.ResponseWriter, r *request.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if item := model.FetchItem(r.Tx, body.ItemID); item == nil {
+	if item = model.FetchItem(r.Tx, body.ItemID); item == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
-	} else if item.Value == 0 {
-		isFAN = true
 	}
 	if payer = model.FetchGuest(r.Tx, guest.PayerID); payer == nil {
 		payer = guest
@@ -73,7 +71,7 @@ func addPurchase(w *request.ResponseWriter, r *request.Request) {
 	// We only accept a single fund-a-need at each level from each guest.
 	// That way we can enter bidder numbers during FAN without worrying
 	// about some of them being duplicates.
-	if isFAN {
+	if item.Type == model.ItemFundANeed {
 		model.FetchPurchases(r.Tx, func(p *model.Purchase) {
 			if p.Unbid {
 				// Reuse the existing purchase record and turn
@@ -85,9 +83,9 @@ func addPurchase(w *request.ResponseWriter, r *request.Request) {
 			}
 		}, "item=? AND guest=?", body.ItemID, body.GuestID)
 	}
-	// Registrations and donations don't need to be "picked up", so we'll
-	// treat them as if they have been.
-	if body.ItemID == 1 || isFAN {
+	// Registrations, donations, and raffle tickets don't need to be
+	// "picked up", so we'll treat them as if they have been.
+	if !item.NeedsPickup() {
 		body.PickedUp = true
 	}
 	if !isDup {
@@ -1,7 +1,6 @@
 package purchase
 
 import (
-	"fmt"
 	"log"
 	"net/http"
 	"sort"
@@ -36,8 +35,8 @@ func serveAuctionWinners(w *request.ResponseWriter, r *request.Request) {
 	// Gather the data.
 	model.FetchPurchases(r.Tx, func(p *model.Purchase) {
 		var item = model.FetchItem(r.Tx, p.ItemID)
-		if item.ID == 1 || item.Value == 0 {
-			// Registration, Donation, Fund-a-Need, etc.
+		if !item.IsAuction() {
+			// Registration, Donation, Fund-a-Need, Raffle, etc.
 			return
 		}
 		var winner = model.FetchGuest(r.Tx, p.GuestID)
@@ -94,7 +93,7 @@ func renderItem(pdf *gofpdf.Fpdf, idx int, item *itemt) {
 	pdf.MoveTo(36, y)
 	pdf.CellFormat(234, 14, item.itemName, "", 1, "LM", false, 0, "")
 	pdf.MoveTo(324, y)
-	pdf.CellFormat(27, 14, fmt.Sprintf("%x", item.bidderNum), "", 1, "RM", false, 0, "")
+	pdf.CellFormat(27, 14, model.FormatBidder(item.bidderNum), "", 1, "RM", false, 0, "")
 	pdf.MoveTo(360, y)
 	pdf.CellFormat(180, 14, item.bidderName, "", 1, "LM", false, 0, "")
 	if !item.prepaid {
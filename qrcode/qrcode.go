@@ -0,0 +1,48 @@
+// Package qrcode draws QR codes on PDF documents.  The codes are drawn as
+// vector graphics, so that they stay sharp at any size and any printer
+// resolution.
+package qrcode
+
+import (
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Draw draws a QR code encoding content, size points square, with its top
+// left corner at (x, y).  The size includes the quiet zone (the blank border
+// that scanners need), which is four modules wide as the QR code standard
+// requires.  Draw does nothing if content is empty.
+func Draw(pdf *gofpdf.Fpdf, content string, x, y, size float64) {
+	if content == "" {
+		return
+	}
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		pdf.SetError(err)
+		return
+	}
+	modules := code.Bounds().Dx()
+	unit := size / float64(modules+8)
+	pdf.SetFillColor(0, 0, 0)
+	for row := 0; row < modules; row++ {
+		for col := 0; col < modules; {
+			// Draw each horizontal run of dark modules as one
+			// rectangle, which keeps the PDF small.
+			if !isDark(code, col, row) {
+				col++
+				continue
+			}
+			start := col
+			for col < modules && isDark(code, col, row) {
+				col++
+			}
+			pdf.Rect(x+float64(start+4)*unit, y+float64(row+4)*unit, float64(col-start)*unit, unit, "F")
+		}
+	}
+}
+
+func isDark(code barcode.Barcode, x, y int) bool {
+	r, _, _, _ := code.At(x, y).RGBA()
+	return r == 0
+}
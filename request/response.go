@@ -2,6 +2,7 @@ package request
 
 import (
 	"compress/gzip"
+	"encoding/json"
 	"io"
 	"net/http"
 	"strings"
@@ -79,3 +80,20 @@ func (w *ResponseWriter) CommitNoContent(r *Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// CommitWarning is like CommitNoContent, except that if the warning is
+// non-empty, it sends a 200 OK response with a JSON {"warning": warning} body
+// instead, so that the client can show the warning to the user.
+func (w *ResponseWriter) CommitWarning(r *Request, warning string) {
+	if warning == "" {
+		w.CommitNoContent(r)
+		return
+	}
+	if err := r.Tx.Commit(); err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(struct {
+		Warning string `json:"warning"`
+	}{warning})
+}
@@ -0,0 +1,94 @@
+package sendmail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maildirTransport delivers messages into a Maildir directory, where they can
+// be read with any Maildir-capable mail client.  Nothing is sent.
+type maildirTransport struct {
+	dir string
+	seq int
+	mu  sync.Mutex
+}
+
+func newMaildirTransport(dir string) (*maildirTransport, error) {
+	if dir == "" {
+		return nil, errors.New("mailTransport maildir requires mailPath")
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &maildirTransport{dir: dir}, nil
+}
+
+// Send delivers the message, writing it first into tmp and then moving it into
+// new, as the Maildir format requires.
+func (t *maildirTransport) Send(by []byte) (err error) {
+	var (
+		name string
+		tmp  string
+	)
+	t.mu.Lock()
+	t.seq++
+	name = fmt.Sprintf("%d.%d_%d.gala", time.Now().UnixNano(), os.Getpid(), t.seq)
+	t.mu.Unlock()
+	tmp = filepath.Join(t.dir, "tmp", name)
+	if err = os.WriteFile(tmp, by, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(t.dir, "new", name))
+}
+
+// mboxTransport appends messages to an mbox file.  Nothing is sent.
+type mboxTransport struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newMboxTransport(path string) (*mboxTransport, error) {
+	if path == "" {
+		return nil, errors.New("mailTransport mbox requires mailPath")
+	}
+	return &mboxTransport{path: path}, nil
+}
+
+// Send appends the message to the file, with the "From " separator line that
+// the mbox format requires, and with any body lines starting with "From "
+// quoted.
+func (t *mboxTransport) Send(by []byte) (err error) {
+	var (
+		fh  *os.File
+		buf bytes.Buffer
+	)
+	fmt.Fprintf(&buf, "From gala %s\n", time.Now().Format(time.ANSIC))
+	by = bytes.ReplaceAll(by, []byte("\r\n"), []byte("\n"))
+	for _, line := range bytes.SplitAfter(by, []byte("\n")) {
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) {
+			buf.WriteByte('>')
+		}
+		buf.Write(line)
+	}
+	if !bytes.HasSuffix(by, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if fh, err = os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		return err
+	}
+	if _, err = fh.Write(buf.Bytes()); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
@@ -6,13 +6,11 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"mime/quotedprintable"
 	"net/textproto"
-	"os"
 	"strings"
-
-	scholaemail "github.com/scholacantorum/schola-email"
 )
 
 //go:embed "mail-logo.png"
@@ -46,27 +44,27 @@ type Message struct {
 	// # is the zero-based index of the image in the Images slice.  They
 	// must be in PNG format.
 	Images [][]byte
-	// To be complete, we should allow attachments as well.  But we don't
-	// need them for anything right now, so that's a someday thing.
+	// Attachments is an optional list of files to attach to the message.
+	Attachments []Attachment
 }
 
-// Send sends an email message.
-func (m *Message) Send() (err error) {
-	by := m.render()
-	if err = scholaemail.Send(by); err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR sending email: %s\n", err)
-	}
-	return err
+// Attachment is a file attached to an outgoing message.
+type Attachment struct {
+	// Filename is the name of the file, as presented to the recipient.
+	Filename string
+	// ContentType is the MIME type of the file, e.g. "application/pdf".
+	ContentType string
+	// Data is the content of the file.
+	Data []byte
 }
 
-func (m *Message) render() []byte {
+// Render renders the message into its RFC 5322 form, ready for sending.
+func (m *Message) Render() []byte {
 	var (
 		buf  bytes.Buffer
 		mw   *multipart.Writer
-		mw2  *multipart.Writer
 		hdr  textproto.MIMEHeader
 		part io.Writer
-		qw   *quotedprintable.Writer
 		bw   io.WriteCloser
 	)
 	if m.From != "" {
@@ -87,17 +85,52 @@ func (m *Message) render() []byte {
 	if m.Subject != "" {
 		fmt.Fprintf(&buf, "Subject: %s\r\n", m.Subject)
 	}
+	if len(m.Attachments) == 0 {
+		m.renderBody(&buf)
+		return buf.Bytes()
+	}
+	// With attachments, the message is multipart/mixed, with the body as
+	// the first part and the attachments following it.
+	mw = multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n--%s\r\n", mw.Boundary(), mw.Boundary())
+	m.renderBody(&buf)
+	io.WriteString(&buf, "\r\n")
+	for _, a := range m.Attachments {
+		hdr = make(textproto.MIMEHeader)
+		hdr.Set("Content-Type", a.ContentType)
+		hdr.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		hdr.Set("Content-Transfer-Encoding", "base64")
+		part, _ = mw.CreatePart(hdr)
+		bw = base64.NewEncoder(base64.StdEncoding, &lineBreaker{w: part})
+		bw.Write(a.Data)
+		bw.Close()
+	}
+	mw.Close()
+	return buf.Bytes()
+}
+
+// renderBody renders the body of the message, including its Content-Type
+// header.
+func (m *Message) renderBody(buf *bytes.Buffer) {
+	var (
+		mw   *multipart.Writer
+		mw2  *multipart.Writer
+		hdr  textproto.MIMEHeader
+		part io.Writer
+		qw   *quotedprintable.Writer
+		bw   io.WriteCloser
+	)
 	switch {
 	case m.Text != "" && m.HTML == "":
-		fmt.Fprintf(&buf, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		fmt.Fprintf(buf, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
 	case m.Text != "" && m.HTML != "":
-		mw = multipart.NewWriter(&buf)
-		fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+		mw = multipart.NewWriter(buf)
+		fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
 	case m.Text == "" && m.HTML != "" && len(m.Images) == 0:
-		fmt.Fprintf(&buf, "Content-Type: text/html; charset=UTF-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+		fmt.Fprintf(buf, "Content-Type: text/html; charset=UTF-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
 	case m.Text == "" && m.HTML != "" && len(m.Images) != 0:
-		mw = multipart.NewWriter(&buf)
-		fmt.Fprintf(&buf, "Content-Type: multipart/related; boundary=%s\r\n\r\n", mw.Boundary())
+		mw = multipart.NewWriter(buf)
+		fmt.Fprintf(buf, "Content-Type: multipart/related; boundary=%s\r\n\r\n", mw.Boundary())
 	}
 	if m.Text != "" {
 		if mw != nil {
@@ -106,7 +139,7 @@ func (m *Message) render() []byte {
 			part, _ = mw.CreatePart(hdr)
 			io.WriteString(part, m.Text)
 		} else {
-			io.WriteString(&buf, m.Text)
+			io.WriteString(buf, m.Text)
 		}
 	}
 	if m.HTML != "" && len(m.Images) == 0 {
@@ -117,7 +150,7 @@ func (m *Message) render() []byte {
 			part, _ := mw.CreatePart(hdr)
 			qw = quotedprintable.NewWriter(part)
 		} else {
-			qw = quotedprintable.NewWriter(&buf)
+			qw = quotedprintable.NewWriter(buf)
 		}
 		io.WriteString(qw, m.HTML)
 		qw.Close()
@@ -156,5 +189,30 @@ func (m *Message) render() []byte {
 	if mw != nil {
 		mw.Close()
 	}
-	return buf.Bytes()
+}
+
+// lineBreaker is a writer that breaks base64 output into lines of 76
+// characters, as required by RFC 2045.
+type lineBreaker struct {
+	w   io.Writer
+	col int
+}
+
+func (lb *lineBreaker) Write(by []byte) (n int, err error) {
+	for len(by) != 0 {
+		chunk := min(len(by), 76-lb.col)
+		if _, err = lb.w.Write(by[:chunk]); err != nil {
+			return n, err
+		}
+		n += chunk
+		lb.col += chunk
+		by = by[chunk:]
+		if lb.col == 76 {
+			if _, err = io.WriteString(lb.w, "\r\n"); err != nil {
+				return n, err
+			}
+			lb.col = 0
+		}
+	}
+	return n, nil
 }
@@ -0,0 +1,94 @@
+package sendmail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+)
+
+// smtpTransport sends messages through an SMTP relay.
+type smtpTransport struct {
+	addr string
+	auth smtp.Auth
+}
+
+func newSMTPTransport(addr, username, password string) (*smtpTransport, error) {
+	var t = smtpTransport{addr: addr}
+
+	if addr == "" {
+		return nil, errors.New("mailTransport smtp requires smtpServer")
+	}
+	if username != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("smtpServer: %s", err)
+		}
+		t.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &t, nil
+}
+
+// Send sends the message.  The envelope sender and recipients are taken from
+// the message headers, and the Bcc header is removed before sending.
+func (t *smtpTransport) Send(by []byte) (err error) {
+	var (
+		msg   *mail.Message
+		from  *mail.Address
+		addrs []*mail.Address
+		rcpts []string
+	)
+	if msg, err = mail.ReadMessage(bytes.NewReader(by)); err != nil {
+		return err
+	}
+	if from, err = mail.ParseAddress(msg.Header.Get("From")); err != nil {
+		return fmt.Errorf("From: %s", err)
+	}
+	for _, hdr := range []string{"To", "Cc", "Bcc"} {
+		if msg.Header.Get(hdr) == "" {
+			continue
+		}
+		if addrs, err = msg.Header.AddressList(hdr); err != nil {
+			return fmt.Errorf("%s: %s", hdr, err)
+		}
+		for _, a := range addrs {
+			rcpts = append(rcpts, a.Address)
+		}
+	}
+	if len(rcpts) == 0 {
+		return errors.New("message has no recipients")
+	}
+	return smtp.SendMail(t.addr, t.auth, from.Address, rcpts, removeBcc(by))
+}
+
+// removeBcc returns a copy of the message with its Bcc header removed.
+func removeBcc(by []byte) []byte {
+	var (
+		out    bytes.Buffer
+		inBcc  bool
+		header []byte
+		body   []byte
+	)
+	if idx := bytes.Index(by, []byte("\r\n\r\n")); idx >= 0 {
+		header, body = by[:idx+2], by[idx+2:]
+	} else {
+		return by
+	}
+	for _, line := range strings.SplitAfter(string(header), "\r\n") {
+		if line != "" && (line[0] == ' ' || line[0] == '\t') {
+			if !inBcc {
+				out.WriteString(line)
+			}
+			continue
+		}
+		inBcc = len(line) >= 4 && strings.EqualFold(line[:4], "bcc:")
+		if !inBcc {
+			out.WriteString(line)
+		}
+	}
+	out.Write(body)
+	return out.Bytes()
+}
@@ -0,0 +1,65 @@
+package sendmail
+
+import (
+	"fmt"
+	"sync"
+
+	scholaemail "github.com/scholacantorum/schola-email"
+
+	"github.com/scholacantorum/gala-backend/config"
+)
+
+// A Transport delivers rendered email messages.
+type Transport interface {
+	// Send delivers a message, which must be in RFC 5322 form with all of
+	// its recipients listed in its To, Cc, and Bcc headers.
+	Send(message []byte) error
+}
+
+var (
+	transport     Transport
+	transportErr  error
+	transportOnce sync.Once
+)
+
+// Send sends a rendered email message, using the transport selected by the
+// mailTransport configuration setting:
+//
+//	"schola" (or empty): the shared Schola Cantorum email service
+//	"smtp":    an SMTP relay at smtpServer (host:port), authenticating with
+//	           smtpUsername and smtpPassword if they are set
+//	"maildir": a Maildir directory at mailPath
+//	"mbox":    an mbox file at mailPath
+//
+// The last two deliver nothing; they are for development and testing.
+func Send(by []byte) error {
+	transportOnce.Do(func() { transport, transportErr = newTransport(config.Get("mailTransport")) })
+	if transportErr != nil {
+		return transportErr
+	}
+	return transport.Send(by)
+}
+
+// newTransport returns a new transport of the named type.
+func newTransport(name string) (Transport, error) {
+	switch name {
+	case "", "schola":
+		return scholaTransport{}, nil
+	case "smtp":
+		return newSMTPTransport(config.Get("smtpServer"), config.Get("smtpUsername"), config.Get("smtpPassword"))
+	case "maildir":
+		return newMaildirTransport(config.Get("mailPath"))
+	case "mbox":
+		return newMboxTransport(config.Get("mailPath"))
+	default:
+		return nil, fmt.Errorf("unknown mailTransport %q", name)
+	}
+}
+
+// scholaTransport sends messages through the shared Schola Cantorum email
+// service.
+type scholaTransport struct{}
+
+func (scholaTransport) Send(by []byte) error {
+	return scholaemail.Send(by)
+}
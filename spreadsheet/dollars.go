@@ -0,0 +1,46 @@
+package spreadsheet
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dollarsRE matches an amount of money: digits, optionally grouped with
+// commas, and optionally followed by cents.
+var dollarsRE = regexp.MustCompile(`^(\d{1,3}(?:,\d{3})+|\d+)(?:\.(\d{1,2}))?$`)
+
+// ParseDollars parses an amount of money in dollars and cents, such as
+// "$1,250", "1250.5", or "1250.50", and returns it in cents.  Negative
+// amounts, and amounts with fractions of a cent, are errors.
+func ParseDollars(value string) (cents int, err error) {
+	var match = dollarsRE.FindStringSubmatch(strings.TrimPrefix(strings.TrimSpace(value), "$"))
+
+	if match == nil {
+		return 0, fmt.Errorf("%q is not an amount in dollars and cents", value)
+	}
+	dollars, err := strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
+	if err != nil {
+		return 0, fmt.Errorf("%q is too large", value)
+	}
+	cents = dollars * 100
+	switch len(match[2]) {
+	case 1:
+		c, _ := strconv.Atoi(match[2])
+		cents += c * 10
+	case 2:
+		c, _ := strconv.Atoi(match[2])
+		cents += c
+	}
+	return cents, nil
+}
+
+// FormatDollars formats an amount of money, given in cents, as dollars, with
+// the cents shown only if there are any: "$1250" or "$1250.50".
+func FormatDollars(cents int) string {
+	if cents%100 == 0 {
+		return fmt.Sprintf("$%d", cents/100)
+	}
+	return fmt.Sprintf("$%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,51 @@
+package spreadsheet
+
+import "testing"
+
+func TestParseDollars(t *testing.T) {
+	tests := []struct {
+		value string
+		cents int
+	}{
+		{"1250", 125000},
+		{"$1,250", 125000},
+		{"1,250,000", 125000000},
+		{"1250.5", 125050},
+		{"1250.50", 125050},
+		{" $12.05 ", 1205},
+		{"0.99", 99},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		if cents, err := ParseDollars(tt.value); err != nil || cents != tt.cents {
+			t.Errorf("ParseDollars(%q) = %d, %v; want %d", tt.value, cents, err, tt.cents)
+		}
+	}
+	for _, value := range []string{"", "$", "-5", "$-5", "1.234", "12,34", "1,2345", "1.", ".50", "12 dollars", "99999999999999999999"} {
+		if cents, err := ParseDollars(value); err == nil {
+			t.Errorf("ParseDollars(%q) = %d; want error", value, cents)
+		}
+	}
+}
+
+func TestFormatDollars(t *testing.T) {
+	tests := []struct {
+		cents int
+		want  string
+	}{
+		{0, "$0"},
+		{5, "$0.05"},
+		{99, "$0.99"},
+		{125000, "$1250"},
+		{125050, "$1250.50"},
+		{4999, "$49.99"},
+	}
+	for _, tt := range tests {
+		if got := FormatDollars(tt.cents); got != tt.want {
+			t.Errorf("FormatDollars(%d) = %q; want %q", tt.cents, got, tt.want)
+		}
+		if back, err := ParseDollars(tt.want); err != nil || back != tt.cents {
+			t.Errorf("ParseDollars(FormatDollars(%d)) = %d, %v", tt.cents, back, err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package spreadsheet reads the tabular files that staff upload for bulk
+// imports: CSV files, and the first worksheet of Excel (XLSX) workbooks.  It
+// also maps the columns of the sheet onto the fields being imported, by
+// header name.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sheet is the content of a spreadsheet.  The first row is the header row,
+// giving the names of the columns.
+type Sheet struct {
+	Header  []string
+	Rows    [][]string // not including the header row
+	Numbers []int      // row number of each of the Rows in the file (1-based)
+}
+
+// Read reads a spreadsheet.  XLSX workbooks are recognized by their content;
+// anything else is treated as CSV.  Rows that are entirely blank are dropped,
+// and every row is padded to the width of the widest row.
+func Read(r io.Reader) (sheet *Sheet, err error) {
+	var (
+		data []byte
+		rows [][]string
+	)
+	if data, err = io.ReadAll(r); err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		rows, err = readXLSX(data)
+	} else {
+		rows, err = readCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newSheet(rows)
+}
+
+// readCSV reads a CSV file.  A leading byte order mark, as Excel writes, is
+// ignored.
+func readCSV(data []byte) (rows [][]string, err error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("CSV file is not in UTF-8")
+	}
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	if rows, err = cr.ReadAll(); err != nil {
+		return nil, fmt.Errorf("CSV file: %s", err)
+	}
+	return rows, nil
+}
+
+// newSheet builds a Sheet from the rows read from a file.
+func newSheet(rows [][]string) (sheet *Sheet, err error) {
+	var width int
+
+	sheet = new(Sheet)
+	for num, row := range rows {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+		if strings.Join(row, "") == "" {
+			continue
+		}
+		width = max(width, len(row))
+		if sheet.Header == nil {
+			sheet.Header = row
+		} else {
+			sheet.Rows = append(sheet.Rows, row)
+			sheet.Numbers = append(sheet.Numbers, num+1)
+		}
+	}
+	if sheet.Header == nil {
+		return nil, errors.New("spreadsheet is empty")
+	}
+	for len(sheet.Header) < width {
+		sheet.Header = append(sheet.Header, "")
+	}
+	for i := range sheet.Rows {
+		for len(sheet.Rows[i]) < width {
+			sheet.Rows[i] = append(sheet.Rows[i], "")
+		}
+	}
+	return sheet, nil
+}
+
+// Field describes a field that can be imported from a spreadsheet.
+type Field struct {
+	// Name is the name of the field, as used in the column mapping.
+	Name string
+	// Headers are the column headers that are recognized as this field
+	// when no mapping is given for it, compared without regard to case,
+	// spaces, or punctuation.  The Name is always recognized.
+	Headers []string
+}
+
+// Columns maps the columns of a sheet onto a set of fields.  It returns the
+// column index of each field found in the sheet.  The mapping gives the header
+// of the column for some or all of the fields; the remaining fields are found
+// by their recognized headers.  It is an error for the mapping to name a
+// column that isn't in the sheet or a field that isn't in the set.
+func (s *Sheet) Columns(fields []Field, mapping map[string]string) (columns map[string]int, err error) {
+	var known = make(map[string]bool)
+
+	columns = make(map[string]int)
+	for _, f := range fields {
+		known[f.Name] = true
+	}
+	for name, header := range mapping {
+		if !known[name] {
+			return nil, fmt.Errorf("no such field %q", name)
+		}
+		if header == "" {
+			continue // explicitly not imported
+		}
+		col := s.column(header)
+		if col < 0 {
+			return nil, fmt.Errorf("no column %q for field %q", header, name)
+		}
+		columns[name] = col
+	}
+	for _, f := range fields {
+		if _, ok := mapping[f.Name]; ok {
+			continue
+		}
+		for _, header := range append([]string{f.Name}, f.Headers...) {
+			if col := s.column(header); col >= 0 {
+				columns[f.Name] = col
+				break
+			}
+		}
+	}
+	return columns, nil
+}
+
+// column returns the index of the column with the specified header, or -1 if
+// there is none.
+func (s *Sheet) column(header string) int {
+	var want = normalize(header)
+
+	for i, h := range s.Header {
+		if normalize(h) == want {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalize returns a header reduced to its lower-case letters and digits, for
+// comparison.
+func normalize(header string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return -1
+		}
+	}, header)
+}
+
+// Value returns the value of the named field in a row, or an empty string if
+// the field isn't in the sheet.
+func Value(row []string, columns map[string]int, field string) string {
+	if col, ok := columns[field]; ok {
+		return row[col]
+	}
+	return ""
+}
+
+// Flag parses a yes/no value from a spreadsheet.  Blank, "no", "n", "false",
+// "0", and "-" are false; anything else (e.g. "yes", "x", "✓") is true.
+func Flag(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "no", "n", "false", "0", "-":
+		return false
+	default:
+		return true
+	}
+}
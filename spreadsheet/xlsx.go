@@ -0,0 +1,197 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// The parts of an XLSX workbook that we read.  Only the cell values are
+// needed; formatting is ignored.
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+type xlsxSharedStrings struct {
+	Items []xlsxString `xml:"si"`
+}
+type xlsxString struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string     `xml:"r,attr"`
+			T  string     `xml:"t,attr"`
+			V  string     `xml:"v"`
+			IS xlsxString `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// text returns the text of a (possibly rich text) string.
+func (s *xlsxString) text() string {
+	var sb strings.Builder
+
+	sb.WriteString(s.T)
+	for _, r := range s.Runs {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+// readXLSX reads the first worksheet of an XLSX workbook.  Numbers are
+// returned as written in the sheet's cells, without their display formatting;
+// in particular, dates appear as day numbers.
+func readXLSX(data []byte) (rows [][]string, err error) {
+	var (
+		zr       *zip.Reader
+		workbook xlsxWorkbook
+		rels     xlsxRelationships
+		strs     xlsxSharedStrings
+		sheet    xlsxWorksheet
+		target   string
+	)
+	if zr, err = zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+		return nil, fmt.Errorf("XLSX file: %s", err)
+	}
+	if err = readXLSXPart(zr, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("XLSX file: no worksheets")
+	}
+	if err = readXLSXPart(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RID {
+			target = rel.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = target[1:]
+	} else {
+		target = path.Join("xl", target)
+	}
+	if err = readXLSXPart(zr, "xl/sharedStrings.xml", &strs); err != nil && !errors.Is(err, errMissingPart) {
+		return nil, err
+	}
+	if err = readXLSXPart(zr, target, &sheet); err != nil {
+		return nil, err
+	}
+	for _, xrow := range sheet.Rows {
+		var row []string
+
+		if len(xrow.Cells) == 0 {
+			continue
+		}
+		num := xrow.R
+		if num < 1 {
+			num = len(rows) + 1
+		}
+		if num > maxRows {
+			return nil, fmt.Errorf("XLSX file: more than %d rows", maxRows)
+		}
+		for len(rows) < num {
+			rows = append(rows, nil)
+		}
+		for _, cell := range xrow.Cells {
+			var value string
+
+			col := columnIndex(cell.R)
+			if col < 0 {
+				col = len(row)
+			}
+			switch cell.T {
+			case "s":
+				idx, err := strconv.Atoi(cell.V)
+				if err != nil || idx < 0 || idx >= len(strs.Items) {
+					return nil, fmt.Errorf("XLSX file: cell %s: bad shared string %q", cell.R, cell.V)
+				}
+				value = strs.Items[idx].text()
+			case "inlineStr":
+				value = cell.IS.text()
+			case "b":
+				value = map[string]string{"0": "FALSE", "1": "TRUE"}[cell.V]
+			case "", "n":
+				value = formatNumber(cell.V)
+			default: // "str" (formula result), "e" (error)
+				value = cell.V
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			row[col] = value
+		}
+		rows[num-1] = row
+	}
+	return rows, nil
+}
+
+// maxRows is the largest number of rows we'll read from a worksheet.  It
+// protects against sheets with stray formatting far down the page.
+const maxRows = 10000
+
+// errMissingPart is returned by readXLSXPart when the part doesn't exist.
+var errMissingPart = errors.New("missing part")
+
+// readXLSXPart reads and decodes the named XML part of an XLSX workbook.
+func readXLSXPart(zr *zip.Reader, name string, into interface{}) (err error) {
+	var fh io.ReadCloser
+
+	for _, f := range zr.File {
+		if f.Name == name {
+			if fh, err = f.Open(); err != nil {
+				return fmt.Errorf("XLSX file: %s: %s", name, err)
+			}
+			defer fh.Close()
+			if err = xml.NewDecoder(fh).Decode(into); err != nil {
+				return fmt.Errorf("XLSX file: %s: %s", name, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("XLSX file: %s: %w", name, errMissingPart)
+}
+
+// columnIndex returns the zero-based column index of a cell reference such as
+// "C12", or -1 if the reference is empty.
+func columnIndex(ref string) (col int) {
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A') + 1
+	}
+	return col - 1
+}
+
+// formatNumber returns a number from a cell, without the floating-point noise
+// that Excel sometimes stores (e.g. "0.30000000000000004" is returned as
+// "0.3").
+func formatNumber(v string) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
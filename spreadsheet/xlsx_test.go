@@ -0,0 +1,141 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// buildXLSX returns an XLSX workbook made of the specified parts.
+func buildXLSX(t *testing.T, parts map[string]string) []byte {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	testWorkbook = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+    xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets><sheet name="Guests" sheetId="1" r:id="rId2"/><sheet name="Other" sheetId="2" r:id="rId1"/></sheets>
+</workbook>`
+	testRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+	testSharedStrings = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>Name</t></si>
+  <si><t>Amount</t></si>
+  <si><r><t>Zoë </t></r><r><t>Roth</t></r></si>
+</sst>`
+	testSheet = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="inlineStr"><is><t>Paid</t></is></c></row>
+    <row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>0.30000000000000004</v></c><c r="D2" t="b"><v>1</v></c></row>
+    <row r="3"/>
+    <row r="5"><c r="B5" t="n"><v>1250</v></c><c r="C5" t="str"><v>formula</v></c></row>
+  </sheetData>
+</worksheet>`
+	testOtherSheet = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>wrong sheet</t></is></c></row></sheetData>
+</worksheet>`
+)
+
+func TestReadXLSX(t *testing.T) {
+	data := buildXLSX(t, map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testRels,
+		"xl/sharedStrings.xml":       testSharedStrings,
+		"xl/worksheets/sheet1.xml":   testSheet,
+		"xl/worksheets/sheet2.xml":   testOtherSheet,
+	})
+	rows, err := readXLSX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Name", "Amount", "", "Paid"},
+		{"Zoë Roth", "0.3", "", "TRUE"},
+		nil,
+		nil,
+		{"", "1250", "formula"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("readXLSX rows = %q; want %q", rows, want)
+	}
+
+	// Through Read, the blank rows are dropped and the rows padded.
+	sheet, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sheet.Header, want[0]) || len(sheet.Rows) != 2 ||
+		!reflect.DeepEqual(sheet.Rows[1], []string{"", "1250", "formula", ""}) ||
+		!reflect.DeepEqual(sheet.Numbers, []int{2, 5}) {
+		t.Errorf("Read = %q %q %v", sheet.Header, sheet.Rows, sheet.Numbers)
+	}
+}
+
+func TestReadXLSXErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts map[string]string
+	}{
+		{"no workbook", map[string]string{"xl/worksheets/sheet1.xml": testSheet}},
+		{"no worksheet", map[string]string{
+			"xl/workbook.xml":            testWorkbook,
+			"xl/_rels/workbook.xml.rels": testRels,
+			"xl/sharedStrings.xml":       testSharedStrings,
+		}},
+		{"bad shared string", map[string]string{
+			"xl/workbook.xml":            testWorkbook,
+			"xl/_rels/workbook.xml.rels": testRels,
+			"xl/worksheets/sheet1.xml":   testSheet, // no sharedStrings.xml
+		}},
+	}
+	for _, tt := range tests {
+		if _, err := readXLSX(buildXLSX(t, tt.parts)); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+	if _, err := readXLSX([]byte("PK\x03\x04 not really a zip file")); err == nil {
+		t.Errorf("not a zip file: no error")
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"A1", 0},
+		{"C12", 2},
+		{"Z9", 25},
+		{"AA3", 26},
+		{"AZ1", 51},
+		{"BA1", 52},
+		{"XFD1048576", 16383},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		if got := columnIndex(tt.ref); got != tt.want {
+			t.Errorf("columnIndex(%q) = %d; want %d", tt.ref, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package table
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// serveAccessibility handles GET /tables/accessibility.pdf.  It returns a
+// report for the venue listing the attending guests who need wheelchair access
+// or hearing assistance, by table.
+func serveAccessibility(w *request.ResponseWriter, r *request.Request) {
+	const (
+		left       = 54.0
+		width      = 504.0
+		tableWidth = 72.0
+		nameWidth  = 180.0
+		lineHeight = 16.0
+	)
+	var (
+		pdf        *gofpdf.Fpdf
+		tr         func(string) string
+		buf        bytes.Buffer
+		needing    []seatedGuest
+		wheelchair int
+		hearing    int
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	for _, sg := range fetchSeatedGuests(r.Tx) {
+		if len(sg.Guest.AccessNeeds()) != 0 {
+			needing = append(needing, sg)
+		}
+		if sg.Guest.Wheelchair {
+			wheelchair++
+		}
+		if sg.Guest.HearingAssist {
+			hearing++
+		}
+	}
+	pdf = gofpdf.New("P", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(left, 54, left)
+	pdf.SetAutoPageBreak(true, 54)
+	pdf.AddPage()
+	pdf.SetFont("helvetica", "B", 16)
+	pdf.CellFormat(width, 22, tr(fmt.Sprintf("%s — Accessibility Needs", config.Get("galaTitle"))), "", 1, "L", false, 0, "")
+	pdf.SetFont("helvetica", "", 11)
+	pdf.CellFormat(width, 16, tr(config.Get("galaDate")), "", 1, "L", false, 0, "")
+	pdf.Ln(8)
+	pdf.CellFormat(width, lineHeight, fmt.Sprintf("Guests needing wheelchair access: %d", wheelchair), "", 1, "L", false, 0, "")
+	pdf.CellFormat(width, lineHeight, fmt.Sprintf("Guests needing hearing assistance: %d", hearing), "", 1, "L", false, 0, "")
+	pdf.Ln(12)
+	if len(needing) == 0 {
+		pdf.SetFont("helvetica", "I", 11)
+		pdf.CellFormat(width, lineHeight, "No attending guests have reported accessibility needs.", "", 1, "L", false, 0, "")
+	} else {
+		pdf.SetFont("helvetica", "B", 11)
+		pdf.CellFormat(tableWidth, lineHeight, "Table", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(nameWidth, lineHeight, "Guest", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(width-tableWidth-nameWidth, lineHeight, "Needs", "B", 1, "L", false, 0, "")
+		pdf.SetFont("helvetica", "", 11)
+		for _, sg := range needing {
+			table := "—"
+			if sg.Table.Number != 0 {
+				table = strconv.Itoa(sg.Table.Number)
+			}
+			pdf.CellFormat(tableWidth, lineHeight, tr(table), "", 0, "L", false, 0, "")
+			pdf.CellFormat(nameWidth, lineHeight, tr(sg.Guest.Name), "", 0, "L", false, 0, "")
+			pdf.CellFormat(width-tableWidth-nameWidth, lineHeight, strings.Join(sg.Guest.AccessNeeds(), ", "), "", 1, "L", false, 0, "")
+		}
+	}
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="gala-accessibility.pdf"`)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,318 @@
+package table
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// seatingPlan is a proposed assignment of unseated parties to numbered tables,
+// as produced by POST /tables/auto-seat and accepted by POST
+// /tables/auto-seat/apply.
+type seatingPlan struct {
+	Assignments []seatingAssignment `json:"assignments"`
+	Unplaced    []unplacedParty     `json:"unplaced"`
+}
+type seatingAssignment struct {
+	Party  db.ID `json:"party"`
+	Table  db.ID `json:"table"`
+	Number int   `json:"number"` // for display only
+	Guests int   `json:"guests"` // for display only
+}
+type unplacedParty struct {
+	Party  db.ID  `json:"party"`
+	Guests int    `json:"guests"`
+	Reason string `json:"reason"`
+}
+
+// seatingTable is a numbered table as seen by the seating planner.
+type seatingTable struct {
+	table   *model.Table
+	open    int
+	parties map[db.ID]bool
+}
+
+// seatingCluster is a set of unseated parties that must be seated together
+// because of "seat with" constraints among them.
+type seatingCluster struct {
+	parties []db.ID
+	size    int
+	anchors map[db.ID]bool // tables of seated parties they should be with
+	notNear map[db.ID]bool // parties they must not be seated with
+}
+
+// serveAutoSeat handles requests starting with /tables/auto-seat.
+func serveAutoSeat(w *request.ResponseWriter, r *request.Request) {
+	var head string
+
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	switch head {
+	case "":
+		servePlanSeating(w, r)
+	case "apply":
+		serveApplySeating(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// servePlanSeating handles POST /tables/auto-seat.  It returns a proposed
+// seating plan for the parties that are not yet at numbered tables.  It does
+// not change anything.
+func servePlanSeating(w *request.ResponseWriter, r *request.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(planSeating(r.Tx))
+}
+
+// planSeating builds a seating plan.  Parties already at numbered tables are
+// never moved.  Each unseated party is placed whole, together with any other
+// unseated parties it should be seated with, and at the same table as any
+// seated party it should be seated with.  No table is filled beyond its
+// capacity, and no party is placed at a table with a party it must not be
+// near.  Groups are placed largest first, each at the table where it fits
+// most snugly, which leaves the most room for the groups that follow.
+// Parties that can't be placed are listed in the plan with the reason.
+func planSeating(tx *sqlx.Tx) (plan *seatingPlan) {
+	var (
+		tables     = make(map[db.ID]*seatingTable)
+		ordered    []*seatingTable
+		partyTable = make(map[db.ID]db.ID)
+		sizes      = make(map[db.ID]int)
+		unseated   []db.ID
+		clusters   []*seatingCluster
+	)
+	plan = &seatingPlan{Assignments: []seatingAssignment{}, Unplaced: []unplacedParty{}}
+	model.FetchTables(tx, func(t *model.Table) {
+		var copy = *t
+		st := &seatingTable{table: &copy, parties: make(map[db.ID]bool)}
+		st.open = copy.EffectiveCapacity() - model.SeatedAtTable(tx, copy.ID)
+		tables[copy.ID] = st
+		ordered = append(ordered, st)
+	}, "num!=0 ORDER BY num")
+	model.FetchParties(tx, func(p *model.Party) {
+		partyTable[p.ID] = p.TableID
+		if st := tables[p.TableID]; st != nil {
+			st.parties[p.ID] = true
+		} else {
+			unseated = append(unseated, p.ID)
+		}
+	}, "1 ORDER BY id")
+	for _, pid := range unseated {
+		model.FetchGuestsInParty(tx, pid, func(g *model.Guest) {
+			if !g.Cancelled {
+				sizes[pid]++
+			}
+		})
+	}
+	clusters = buildSeatingClusters(tx, unseated, sizes, partyTable, tables)
+	for _, c := range clusters {
+		st, reason := chooseSeatingTable(c, ordered, tables)
+		if st == nil {
+			for _, pid := range c.parties {
+				plan.Unplaced = append(plan.Unplaced, unplacedParty{pid, sizes[pid], reason})
+			}
+			continue
+		}
+		st.open -= c.size
+		for _, pid := range c.parties {
+			st.parties[pid] = true
+			plan.Assignments = append(plan.Assignments, seatingAssignment{pid, st.table.ID, st.table.Number, sizes[pid]})
+		}
+	}
+	return plan
+}
+
+// buildSeatingClusters groups the unseated parties (those with at least one
+// guest) into clusters joined by "seat with" constraints, and returns them in
+// the order they should be placed: those anchored to a seated party first,
+// then largest first.
+func buildSeatingClusters(
+	tx *sqlx.Tx, unseated []db.ID, sizes map[db.ID]int, partyTable map[db.ID]db.ID, tables map[db.ID]*seatingTable,
+) (clusters []*seatingCluster) {
+	var (
+		parent  = make(map[db.ID]db.ID)
+		find    func(db.ID) db.ID
+		byRoot  = make(map[db.ID]*seatingCluster)
+		with    = make(map[db.ID][]db.ID)
+		notNear = make(map[db.ID][]db.ID)
+	)
+	find = func(id db.ID) db.ID {
+		for parent[id] != id {
+			id = parent[id]
+		}
+		return id
+	}
+	for _, pid := range unseated {
+		if sizes[pid] != 0 {
+			parent[pid] = pid
+		}
+	}
+	for _, pid := range unseated {
+		if sizes[pid] == 0 {
+			continue
+		}
+		with[pid], notNear[pid] = model.FetchPartyConstraints(tx, pid)
+		for _, other := range with[pid] {
+			if _, ok := parent[other]; ok {
+				parent[find(other)] = find(pid)
+			}
+		}
+	}
+	for _, pid := range unseated {
+		if sizes[pid] == 0 {
+			continue
+		}
+		root := find(pid)
+		c := byRoot[root]
+		if c == nil {
+			c = &seatingCluster{anchors: make(map[db.ID]bool), notNear: make(map[db.ID]bool)}
+			byRoot[root] = c
+			clusters = append(clusters, c)
+		}
+		c.parties = append(c.parties, pid)
+		c.size += sizes[pid]
+		for _, other := range with[pid] {
+			if tables[partyTable[other]] != nil {
+				c.anchors[partyTable[other]] = true
+			}
+		}
+		for _, other := range notNear[pid] {
+			c.notNear[other] = true
+		}
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if (len(clusters[i].anchors) != 0) != (len(clusters[j].anchors) != 0) {
+			return len(clusters[i].anchors) != 0
+		}
+		return clusters[i].size > clusters[j].size
+	})
+	return clusters
+}
+
+// chooseSeatingTable returns the table at which a cluster should be placed.
+// If there is none, it returns nil and the reason.
+func chooseSeatingTable(c *seatingCluster, ordered []*seatingTable, tables map[db.ID]*seatingTable) (
+	best *seatingTable, reason string,
+) {
+	var candidates = ordered
+
+	for _, pid := range c.parties {
+		if c.notNear[pid] {
+			return nil, "conflicting seat-with and not-near constraints"
+		}
+	}
+	switch len(c.anchors) {
+	case 0:
+		break
+	case 1:
+		for tid := range c.anchors {
+			candidates = []*seatingTable{tables[tid]}
+		}
+	default:
+		return nil, "parties to be seated with are at different tables"
+	}
+	reason = "no table has room"
+	if len(c.anchors) != 0 {
+		reason = fmt.Sprintf("table %d does not have room", candidates[0].table.Number)
+	}
+	for _, st := range candidates {
+		if st.open < c.size {
+			continue
+		}
+		if conflictsWith(c, st) {
+			reason = "no table with room is free of not-near conflicts"
+			if len(c.anchors) != 0 {
+				reason = fmt.Sprintf("table %d has a party it must not be near", st.table.Number)
+			}
+			continue
+		}
+		if best == nil || st.open < best.open {
+			best = st
+		}
+	}
+	return best, reason
+}
+
+// conflictsWith returns whether the table has a party that the cluster must
+// not be seated near.
+func conflictsWith(c *seatingCluster, st *seatingTable) bool {
+	for pid := range c.notNear {
+		if st.parties[pid] {
+			return true
+		}
+	}
+	return false
+}
+
+// serveApplySeating handles POST /tables/auto-seat/apply.  It takes a seating
+// plan as returned by POST /tables/auto-seat and moves the parties to their
+// assigned tables, in a single journal entry.  It fails with 409 Conflict if
+// any of the parties has been seated since the plan was made.
+func serveApplySeating(w *request.ResponseWriter, r *request.Request) {
+	var (
+		plan     seatingPlan
+		je       model.JournalEntry
+		before   map[db.ID]int
+		warnings []string
+		targets  = make(map[db.ID]bool)
+		err      error
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err = json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		log.Printf("serveApplySeating JSON decode %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	before = model.SeatingCounts(r.Tx)
+	for _, a := range plan.Assignments {
+		party := model.FetchParty(r.Tx, a.Party)
+		table := model.FetchTable(r.Tx, a.Table)
+		if party == nil || table == nil || table.Number == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if current := model.FetchTable(r.Tx, party.TableID); current.Number != 0 {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, "Party %d has already been seated at table %d.", party.ID, current.Number)
+			return
+		}
+		party.TableID = table.ID
+		party.Save(r.Tx, &je)
+		targets[table.ID] = true
+	}
+	for tid := range targets {
+		if warning := model.OverfillWarning(r.Tx, before, tid); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	sort.Strings(warnings)
+	if len(warnings) != 0 && model.RefuseOverfill() {
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, strings.Join(warnings, "\n"))
+		return
+	}
+	journal.Log(r, &je)
+	w.CommitWarning(r, strings.Join(warnings, "\n"))
+}
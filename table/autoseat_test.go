@@ -0,0 +1,82 @@
+package table
+
+import (
+	"testing"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/db/dbtest"
+	"github.com/scholacantorum/gala-backend/model"
+)
+
+func TestPlanSeating(t *testing.T) {
+	tx := dbtest.OpenTx(t)
+	tx.MustExec(`INSERT INTO gtable (id, num, capacity) VALUES (1,0,0), (2,1,8), (3,2,4), (4,3,6)`)
+	tx.MustExec(`INSERT INTO party (id, gtable, place) VALUES (10,2,1), (11,1,1), (12,1,2), (13,1,3), (14,1,4), (15,1,5)`)
+	guests := map[db.ID]int{10: 3, 11: 4, 12: 2, 13: 5, 14: 1, 15: 7}
+	for pid, count := range guests {
+		for range count {
+			tx.MustExec(`INSERT INTO guest (name, sortname, party, cancelled) VALUES ('Guest','Guest',?,?)`, pid, pid == 14)
+		}
+	}
+	tx.MustExec(`INSERT INTO partyConstraint (party, other, kind) VALUES (10,12,'seatWith'), (10,13,'notNear')`)
+
+	plan := planSeating(tx)
+	got := make(map[db.ID]db.ID)
+	for _, a := range plan.Assignments {
+		got[a.Party] = a.Table
+	}
+	// Party 12 goes with the seated party 10.  Party 13 can't sit with 10,
+	// and takes the only other table with room for 5.  Party 11 then fits
+	// exactly at table 3.  Party 14 has no uncancelled guests.
+	want := map[db.ID]db.ID{12: 2, 13: 4, 11: 3}
+	if len(got) != len(want) {
+		t.Errorf("assignments = %v; want %v", got, want)
+	}
+	for pid, tid := range want {
+		if got[pid] != tid {
+			t.Errorf("party %d at table %d; want %d", pid, got[pid], tid)
+		}
+	}
+	if len(plan.Unplaced) != 1 || plan.Unplaced[0].Party != 15 || plan.Unplaced[0].Reason != "no table has room" {
+		t.Errorf("unplaced = %+v; want party 15, no table has room", plan.Unplaced)
+	}
+}
+
+func TestChooseSeatingTable(t *testing.T) {
+	var (
+		one    = &seatingTable{table: &model.Table{ID: 1, Number: 1}, open: 4, parties: map[db.ID]bool{101: true}}
+		two    = &seatingTable{table: &model.Table{ID: 2, Number: 2}, open: 6, parties: map[db.ID]bool{102: true}}
+		three  = &seatingTable{table: &model.Table{ID: 3, Number: 3}, open: 2, parties: map[db.ID]bool{}}
+		tables = map[db.ID]*seatingTable{1: one, 2: two, 3: three}
+		order  = []*seatingTable{one, two, three}
+	)
+	tests := []struct {
+		name    string
+		cluster seatingCluster
+		want    *seatingTable
+		reason  string
+	}{
+		{"snuggest fit", seatingCluster{parties: []db.ID{1}, size: 2}, three, ""},
+		{"only room", seatingCluster{parties: []db.ID{1}, size: 5}, two, ""},
+		{"no room", seatingCluster{parties: []db.ID{1}, size: 7}, nil, "no table has room"},
+		{"not near", seatingCluster{parties: []db.ID{1}, size: 4, notNear: map[db.ID]bool{101: true}}, two, ""},
+		{"no room free of conflicts", seatingCluster{parties: []db.ID{1}, size: 5, notNear: map[db.ID]bool{102: true}},
+			nil, "no table with room is free of not-near conflicts"},
+		{"anchored", seatingCluster{parties: []db.ID{1}, size: 4, anchors: map[db.ID]bool{2: true}}, two, ""},
+		{"anchored without room", seatingCluster{parties: []db.ID{1}, size: 5, anchors: map[db.ID]bool{1: true}},
+			nil, "table 1 does not have room"},
+		{"anchored with conflict",
+			seatingCluster{parties: []db.ID{1}, size: 1, anchors: map[db.ID]bool{1: true}, notNear: map[db.ID]bool{101: true}},
+			nil, "table 1 has a party it must not be near"},
+		{"anchored at two tables", seatingCluster{parties: []db.ID{1}, size: 1, anchors: map[db.ID]bool{1: true, 2: true}},
+			nil, "parties to be seated with are at different tables"},
+		{"with and not near", seatingCluster{parties: []db.ID{1, 2}, size: 2, notNear: map[db.ID]bool{2: true}},
+			nil, "conflicting seat-with and not-near constraints"},
+	}
+	for _, tt := range tests {
+		best, reason := chooseSeatingTable(&tt.cluster, order, tables)
+		if best != tt.want || (best == nil && reason != tt.reason) {
+			t.Errorf("%s: got %v, %q; want %v, %q", tt.name, best, reason, tt.want, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package table
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// serveEntreeCards handles GET /tables/entree-cards.pdf.  It returns a PDF of
+// entree cards, one for each attending guest, to be placed at their seats so
+// that the servers know what to serve them.  The PDF is designed to be printed
+// on sheets of 3.5" x 2" business cards, with 5 rows of 2 cards on each sheet.
+// The sheets have 0.75" margins on left and right and 0.5" margins at top and
+// bottom, with no gutters between cards.  The cards are in table and seat
+// order.
+func serveEntreeCards(w *request.ResponseWriter, r *request.Request) {
+	var (
+		seated  []seatedGuest
+		entrees = make(map[string]*model.Entree)
+		pdf     *gofpdf.Fpdf
+		tr      func(string) string
+		buf     bytes.Buffer
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	seated = fetchSeatedGuests(r.Tx)
+	model.FetchEntrees(r.Tx, func(e *model.Entree) {
+		var copy = *e
+		entrees[e.Code] = &copy
+	}, "")
+	pdf = gofpdf.New("P", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	for i, sg := range seated {
+		if i%10 == 0 {
+			pdf.AddPage()
+		}
+		renderEntreeCard(pdf, tr, sg, entrees[sg.Guest.Entree], 54+252*float64(i%2), 36+144*float64(i%10/2))
+	}
+	if len(seated) == 0 {
+		pdf.AddPage()
+	}
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="entree-cards.pdf"`)
+	w.Write(buf.Bytes())
+}
+
+// renderEntreeCard renders a single entree card with its top left corner at
+// (left, top).  entree is nil if the guest hasn't chosen one, or their choice
+// is no longer in the catalog.
+func renderEntreeCard(pdf *gofpdf.Fpdf, tr func(string) string, sg seatedGuest, entree *model.Entree, left, top float64) {
+	const inset = 14.0
+	var width = 252 - 2*inset
+
+	pdf.SetFont("helvetica", "B", 14)
+	pdf.SetXY(left+inset, top+inset)
+	pdf.CellFormat(width, 18, tr(sg.Guest.Name), "", 0, "LT", false, 0, "")
+	pdf.SetFont("helvetica", "", 11)
+	pdf.SetXY(left+inset, top+inset+18)
+	pdf.CellFormat(width, 14, seatLabel(sg), "", 0, "LT", false, 0, "")
+	pdf.SetXY(left+inset, top+inset+38)
+	switch {
+	case entree != nil:
+		pdf.SetFont("helvetica", "B", 20)
+		pdf.CellFormat(width, 24, tr(entree.Name), "", 0, "CM", false, 0, "")
+	case sg.Guest.Entree != "":
+		pdf.SetFont("helvetica", "B", 20)
+		pdf.CellFormat(width, 24, tr(sg.Guest.Entree), "", 0, "CM", false, 0, "")
+	default:
+		pdf.SetFont("helvetica", "I", 14)
+		pdf.CellFormat(width, 24, "Entree not selected", "", 0, "CM", false, 0, "")
+	}
+	if needs := sg.Guest.DietaryNeeds(); len(needs) != 0 {
+		pdf.SetFont("helvetica", "B", 9)
+		pdf.SetXY(left+inset, top+inset+68)
+		pdf.MultiCell(width, 11, tr(strings.Join(needs, "; ")), "", "C", false)
+	}
+}
@@ -0,0 +1,302 @@
+package table
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// catererReport is the content of the caterer report: the count of each
+// entree and dietary restriction at each table, with the dietary notes for the
+// guests at the table.
+type catererReport struct {
+	Entrees    []catererEntree
+	Tables     []*catererTable
+	Totals     catererTable
+	Unassigned *catererTable // not in Totals; nil if there are none
+}
+type catererEntree struct {
+	Code string
+	Name string
+}
+type catererTable struct {
+	Label      string
+	Counts     map[string]int // keyed by entree code
+	Unselected int
+	Total      int
+	GlutenFree int
+	Vegetarian int
+	Notes      []string
+}
+
+// buildCatererReport walks the tables, parties, and guests to build the
+// caterer report.  Cancelled guests are left out.  Guests who are not at a
+// numbered table (including the donors and staff who are deliberately left
+// there because they aren't dining) are counted together under "Unassigned",
+// which is reported separately and not included in the totals.
+func buildCatererReport(tx *sqlx.Tx) (report *catererReport) {
+	var (
+		tables     []*model.Table
+		unassigned = &catererTable{Label: "Unassigned (not in totals)", Counts: make(map[string]int)}
+		known      = make(map[string]bool)
+		extra      []string
+	)
+	report = &catererReport{Totals: catererTable{Label: "TOTAL", Counts: make(map[string]int)}}
+	model.FetchEntrees(tx, func(e *model.Entree) {
+		report.Entrees = append(report.Entrees, catererEntree{e.Code, e.Name})
+		known[e.Code] = true
+	}, "1 ORDER BY id")
+	model.FetchTables(tx, func(t *model.Table) {
+		var copy = *t
+		tables = append(tables, &copy)
+	}, "1 ORDER BY num")
+	for _, t := range tables {
+		ct := unassigned
+		if t.Number != 0 {
+			ct = &catererTable{Label: strconv.Itoa(t.Number), Counts: make(map[string]int)}
+			if t.Name != "" {
+				ct.Label += " (" + t.Name + ")"
+			}
+		}
+		model.FetchPartiesAtTable(tx, t.ID, func(p *model.Party) {
+			addPartyToCatererTable(tx, ct, p)
+		})
+		if ct != unassigned && ct.Total != 0 {
+			report.Tables = append(report.Tables, ct)
+		}
+	}
+	if unassigned.Total != 0 {
+		report.Unassigned = unassigned
+	}
+	for _, ct := range report.Tables {
+		for code, count := range ct.Counts {
+			report.Totals.Counts[code] += count
+		}
+		report.Totals.Unselected += ct.Unselected
+		report.Totals.Total += ct.Total
+		report.Totals.GlutenFree += ct.GlutenFree
+		report.Totals.Vegetarian += ct.Vegetarian
+	}
+	// Guests may have chosen entrees that have since been removed from the
+	// catalog; they still need to be counted.
+	for _, ct := range append(report.Tables, unassigned) {
+		for code := range ct.Counts {
+			if !known[code] {
+				known[code] = true
+				extra = append(extra, code)
+			}
+		}
+	}
+	sort.Strings(extra)
+	for _, code := range extra {
+		report.Entrees = append(report.Entrees, catererEntree{code, code})
+	}
+	return report
+}
+
+// addPartyToCatererTable adds the (non-cancelled) guests in the party to the
+// caterer report for their table.  Each guest's allergies are noted.  Their
+// special requests are noted too, but since those are usually entered once
+// for a whole party, identical requests within a party are listed only once.
+func addPartyToCatererTable(tx *sqlx.Tx, ct *catererTable, p *model.Party) {
+	var (
+		names = make(map[string][]string)
+		order []string
+	)
+	model.FetchGuestsInParty(tx, p.ID, func(g *model.Guest) {
+		if g.Cancelled {
+			return
+		}
+		ct.Total++
+		if g.Entree == "" {
+			ct.Unselected++
+		} else {
+			ct.Counts[g.Entree]++
+		}
+		if g.GlutenFree {
+			ct.GlutenFree++
+		}
+		if g.Vegetarian {
+			ct.Vegetarian++
+		}
+		if g.Allergies != "" {
+			ct.Notes = append(ct.Notes, g.Name+": allergies: "+g.Allergies)
+		}
+		if req := strings.Join(strings.Fields(g.Requests), " "); req != "" {
+			if names[req] == nil {
+				order = append(order, req)
+			}
+			names[req] = append(names[req], g.Name)
+		}
+	})
+	for _, req := range order {
+		ct.Notes = append(ct.Notes, strings.Join(names[req], ", ")+": "+req)
+	}
+}
+
+// serveCatererCSV handles GET /tables/caterer.csv.  It returns the caterer
+// report as a CSV file, with one row per table and a totals row, followed by
+// the row of unassigned guests if there are any.
+func serveCatererCSV(w *request.ResponseWriter, r *request.Request) {
+	var (
+		cw     *csv.Writer
+		report *catererReport
+		header []string
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	report = buildCatererReport(r.Tx)
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="gala-caterer.csv"`)
+	cw = csv.NewWriter(w)
+	cw.UseCRLF = true
+	header = []string{"Table"}
+	for _, e := range report.Entrees {
+		header = append(header, e.Name)
+	}
+	header = append(header, "Not Selected", "Total", "Gluten Free", "Vegetarian", "Dietary Notes")
+	cw.Write(header)
+	rows := append(report.Tables, &report.Totals)
+	if report.Unassigned != nil {
+		rows = append(rows, report.Unassigned)
+	}
+	for _, ct := range rows {
+		fields := []string{ct.Label}
+		for _, e := range report.Entrees {
+			fields = append(fields, strconv.Itoa(ct.Counts[e.Code]))
+		}
+		fields = append(fields, strconv.Itoa(ct.Unselected), strconv.Itoa(ct.Total), strconv.Itoa(ct.GlutenFree),
+			strconv.Itoa(ct.Vegetarian), strings.Join(ct.Notes, "; "))
+		cw.Write(fields)
+	}
+	cw.Flush()
+}
+
+// serveCatererPDF handles GET /tables/caterer.pdf.  It returns the caterer
+// report as a PDF, with a row of counts for each table followed by the
+// dietary notes for that table.  The unassigned guests come after the totals.
+func serveCatererPDF(w *request.ResponseWriter, r *request.Request) {
+	const (
+		left       = 36.0
+		width      = 720.0
+		labelWidth = 96.0
+		lineHeight = 14.0
+	)
+	var (
+		report    *catererReport
+		pdf       *gofpdf.Fpdf
+		tr        func(string) string
+		colWidth  float64
+		headings  []string
+		buf       bytes.Buffer
+		headerRow func()
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	report = buildCatererReport(r.Tx)
+	for _, e := range report.Entrees {
+		headings = append(headings, e.Name)
+	}
+	headings = append(headings, "Not Selected", "Total", "Gluten Free", "Vegetarian")
+	colWidth = (width - labelWidth) / float64(len(headings))
+
+	pdf = gofpdf.New("L", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(left, 36, left)
+	pdf.SetAutoPageBreak(true, 36)
+	headerRow = func() {
+		pdf.SetFont("helvetica", "B", 10)
+		pdf.CellFormat(labelWidth, lineHeight, "Table", "B", 0, "L", false, 0, "")
+		for _, h := range headings {
+			pdf.CellFormat(colWidth, lineHeight, tr(h), "B", 0, "R", false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("helvetica", "B", 14)
+		pdf.CellFormat(width, 20, tr(fmt.Sprintf("%s — Entree Counts by Table", config.Get("galaTitle"))), "", 1, "L", false, 0, "")
+		pdf.SetFont("helvetica", "", 10)
+		pdf.CellFormat(width, 14, tr(config.Get("galaDate")), "", 1, "L", false, 0, "")
+		pdf.Ln(6)
+		headerRow()
+	})
+	pdf.AddPage()
+	renderTable := func(ct *catererTable) {
+		// Keep the table's counts and its notes together on a page.
+		_, pageHeight := pdf.GetPageSize()
+		if pdf.GetY()+lineHeight*float64(1+len(ct.Notes)) > pageHeight-36 {
+			pdf.AddPage()
+		}
+		renderCatererRow(pdf, tr, report, ct, labelWidth, colWidth, lineHeight)
+		pdf.SetFont("helvetica", "I", 9)
+		for _, note := range ct.Notes {
+			pdf.SetX(left + 12)
+			pdf.MultiCell(width-12, 11, tr(note), "", "L", false)
+		}
+		pdf.Ln(4)
+	}
+	for _, ct := range report.Tables {
+		renderTable(ct)
+	}
+	pdf.SetFont("helvetica", "B", 10)
+	renderCatererRow(pdf, tr, report, &report.Totals, labelWidth, colWidth, lineHeight)
+	if report.Unassigned != nil {
+		pdf.Ln(12)
+		renderTable(report.Unassigned)
+	}
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="gala-caterer.pdf"`)
+	w.Write(buf.Bytes())
+}
+
+// renderCatererRow renders the row of counts for a single table (or the
+// totals) in the caterer report.
+func renderCatererRow(
+	pdf *gofpdf.Fpdf, tr func(string) string, report *catererReport, ct *catererTable,
+	labelWidth, colWidth, lineHeight float64,
+) {
+	var border = ""
+
+	if ct == &report.Totals {
+		border = "T"
+	} else {
+		pdf.SetFont("helvetica", "", 10)
+	}
+	pdf.CellFormat(labelWidth, lineHeight, tr(ct.Label), border, 0, "L", false, 0, "")
+	for _, e := range report.Entrees {
+		pdf.CellFormat(colWidth, lineHeight, strconv.Itoa(ct.Counts[e.Code]), border, 0, "R", false, 0, "")
+	}
+	pdf.CellFormat(colWidth, lineHeight, strconv.Itoa(ct.Unselected), border, 0, "R", false, 0, "")
+	pdf.CellFormat(colWidth, lineHeight, strconv.Itoa(ct.Total), border, 0, "R", false, 0, "")
+	pdf.CellFormat(colWidth, lineHeight, strconv.Itoa(ct.GlutenFree), border, 0, "R", false, 0, "")
+	pdf.CellFormat(colWidth, lineHeight, strconv.Itoa(ct.Vegetarian), border, 1, "R", false, 0, "")
+}
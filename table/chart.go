@@ -0,0 +1,200 @@
+package table
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// Seating chart geometry, in points.  The chart is drawn on a landscape letter
+// page (or an SVG of the same size).  Each table is drawn as a circle with its
+// number in it, with its name and guest list below it; chartCellWidth and
+// chartCellHeight are the space reserved for all of that.
+const (
+	chartPageWidth  = 792.0
+	chartPageHeight = 612.0
+	chartMargin     = 36.0
+	chartTitle      = 24.0 // height of title area
+	chartRadius     = 14.0
+	chartCellWidth  = 96.0
+	chartCellHeight = 108.0
+	chartNameSize   = 7.0 // font size for table names
+	chartGuestSize  = 6.0 // font size for guest names
+)
+
+// chartTable is a table as it is to be drawn on the seating chart.  X and Y
+// are the page coordinates of the center of its circle.
+type chartTable struct {
+	Number int
+	Name   string
+	X, Y   float64
+	Guests []string
+}
+
+// layoutChart returns the tables to be drawn on the seating chart, with their
+// positions scaled from the table map's positions to fit on the page.  Only
+// numbered tables that have been placed on the map are included.  Guests are
+// listed in seat order, with their seat numbers, and cancelled guests are left
+// out.
+func layoutChart(tx *sqlx.Tx) (tables []*chartTable) {
+	var (
+		byID                   = make(map[db.ID]*chartTable)
+		minX, minY, maxX, maxY = math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		scale                  float64
+	)
+	model.FetchTables(tx, func(t *model.Table) {
+		ct := &chartTable{Number: t.Number, Name: t.Name, X: float64(t.X), Y: float64(t.Y)}
+		tables = append(tables, ct)
+		byID[t.ID] = ct
+		minX, minY = math.Min(minX, ct.X), math.Min(minY, ct.Y)
+		maxX, maxY = math.Max(maxX, ct.X), math.Max(maxY, ct.Y)
+	}, "num!=0 AND (x!=0 OR y!=0) ORDER BY num")
+	if len(tables) == 0 {
+		return nil
+	}
+	for _, sg := range fetchSeatedGuests(tx) {
+		if ct := byID[sg.Table.ID]; ct != nil {
+			if sg.Guest.Seat != 0 {
+				ct.Guests = append(ct.Guests, fmt.Sprintf("%d. %s", sg.Guest.Seat, sg.Guest.Name))
+			} else {
+				ct.Guests = append(ct.Guests, sg.Guest.Name)
+			}
+		}
+	}
+	// Scale the map so that the table cells fit within the margins, but
+	// never enlarge it.
+	scale = 1
+	if maxX > minX {
+		scale = math.Min(scale, (chartPageWidth-2*chartMargin-chartCellWidth)/(maxX-minX))
+	}
+	if maxY > minY {
+		scale = math.Min(scale, (chartPageHeight-2*chartMargin-chartTitle-chartCellHeight)/(maxY-minY))
+	}
+	for _, ct := range tables {
+		ct.X = chartMargin + chartCellWidth/2 + (ct.X-minX)*scale
+		ct.Y = chartMargin + chartTitle + chartRadius + (ct.Y-minY)*scale
+	}
+	return tables
+}
+
+// chartHeading returns the title of the seating chart.
+func chartHeading() string {
+	return fmt.Sprintf("%s — Seating Chart", config.Get("galaTitle"))
+}
+
+// serveChartPDF handles GET /tables/chart.pdf.  It returns the seating chart as
+// a PDF.
+func serveChartPDF(w *request.ResponseWriter, r *request.Request) {
+	var (
+		pdf *gofpdf.Fpdf
+		tr  func(string) string
+		buf bytes.Buffer
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	pdf = gofpdf.New("L", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	pdf.SetFont("helvetica", "B", 16)
+	pdf.SetXY(chartMargin, chartMargin)
+	pdf.CellFormat(chartPageWidth-2*chartMargin, chartTitle, tr(chartHeading()), "", 0, "CT", false, 0, "")
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(1)
+	for _, ct := range layoutChart(r.Tx) {
+		left := ct.X - chartCellWidth/2
+		pdf.Circle(ct.X, ct.Y, chartRadius, "D")
+		pdf.SetFont("helvetica", "B", 12)
+		pdf.SetXY(ct.X-chartRadius, ct.Y-chartRadius)
+		pdf.CellFormat(2*chartRadius, 2*chartRadius, strconv.Itoa(ct.Number), "", 0, "CM", false, 0, "")
+		y := ct.Y + chartRadius + 2
+		if ct.Name != "" {
+			pdf.SetFont("helvetica", "B", chartNameSize)
+			pdf.SetXY(left, y)
+			pdf.CellFormat(chartCellWidth, chartNameSize+1, tr(ct.Name), "", 0, "CT", false, 0, "")
+			y += chartNameSize + 1
+		}
+		pdf.SetFont("helvetica", "", chartGuestSize)
+		for _, g := range ct.Guests {
+			pdf.SetXY(left, y)
+			pdf.CellFormat(chartCellWidth, chartGuestSize+1, tr(g), "", 0, "CT", false, 0, "")
+			y += chartGuestSize + 1
+		}
+	}
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="seating-chart.pdf"`)
+	w.Write(buf.Bytes())
+}
+
+// serveChartSVG handles GET /tables/chart.svg.  It returns the seating chart as
+// an SVG image, with the same layout as the PDF.
+func serveChartSVG(w *request.ResponseWriter, r *request.Request) {
+	var buf bytes.Buffer
+
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%.0fpt" height="%.0fpt" viewBox="0 0 %.0f %.0f" font-family="Helvetica, Arial, sans-serif">
+<rect width="100%%" height="100%%" fill="white"/>
+<text x="%.1f" y="%.1f" font-size="16" font-weight="bold" text-anchor="middle" dominant-baseline="hanging">%s</text>
+`, chartPageWidth, chartPageHeight, chartPageWidth, chartPageHeight, chartPageWidth/2, chartMargin, svgEscape(chartHeading()))
+	for _, ct := range layoutChart(r.Tx) {
+		fmt.Fprintf(&buf, `<g><circle cx="%.1f" cy="%.1f" r="%.1f" fill="none" stroke="black"/>
+<text x="%.1f" y="%.1f" font-size="12" font-weight="bold" text-anchor="middle" dominant-baseline="central">%d</text>
+`, ct.X, ct.Y, chartRadius, ct.X, ct.Y, ct.Number)
+		y := ct.Y + chartRadius + 2
+		if ct.Name != "" {
+			fmt.Fprintf(&buf, `<text x="%.1f" y="%.1f" font-size="%.1f" font-weight="bold" text-anchor="middle" dominant-baseline="hanging">%s</text>
+`, ct.X, y, chartNameSize, svgEscape(ct.Name))
+			y += chartNameSize + 1
+		}
+		for _, g := range ct.Guests {
+			fmt.Fprintf(&buf, `<text x="%.1f" y="%.1f" font-size="%.1f" text-anchor="middle" dominant-baseline="hanging">%s</text>
+`, ct.X, y, chartGuestSize, svgEscape(g))
+			y += chartGuestSize + 1
+		}
+		buf.WriteString("</g>\n")
+	}
+	buf.WriteString("</svg>\n")
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="seating-chart.svg"`)
+	w.Write(buf.Bytes())
+}
+
+// svgEscape escapes a string for inclusion in SVG text.
+func svgEscape(s string) string {
+	var buf bytes.Buffer
+
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,76 @@
+package table
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// servePlaceCards handles GET /tables/place-cards.pdf.  It returns a PDF of
+// folded place cards, one for each attending guest, showing their name, table
+// and seat.  Each card is 4.25" x 2.75", with 2 columns of 4 cards on each
+// letter-size sheet and no margins or gutters; folded across the middle, it
+// stands as a 4.25" x 1.375" tent.  The card is printed on both halves, with
+// the top half upside down, so that it can be read from either side of the
+// table.  The cards are in table and seat order.
+func servePlaceCards(w *request.ResponseWriter, r *request.Request) {
+	var (
+		seated []seatedGuest
+		pdf    *gofpdf.Fpdf
+		tr     func(string) string
+		buf    bytes.Buffer
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	seated = fetchSeatedGuests(r.Tx)
+	pdf = gofpdf.New("P", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	for i, sg := range seated {
+		if i%8 == 0 {
+			pdf.AddPage()
+		}
+		left, top := 306*float64(i%2), 198*float64(i%8/2)
+		renderPlaceCardHalf(pdf, tr, sg, left, top+99)
+		pdf.TransformBegin()
+		pdf.TransformRotate(180, left+153, top+49.5)
+		renderPlaceCardHalf(pdf, tr, sg, left, top)
+		pdf.TransformEnd()
+	}
+	if len(seated) == 0 {
+		pdf.AddPage()
+	}
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="place-cards.pdf"`)
+	w.Write(buf.Bytes())
+}
+
+// renderPlaceCardHalf renders one half of a place card, with its top left
+// corner at (left, top).
+func renderPlaceCardHalf(pdf *gofpdf.Fpdf, tr func(string) string, sg seatedGuest, left, top float64) {
+	const inset = 18.0
+	var width = 306 - 2*inset
+
+	pdf.SetFont("helvetica", "B", 20)
+	pdf.SetXY(left+inset, top+24)
+	pdf.CellFormat(width, 26, tr(sg.Guest.Name), "", 0, "CM", false, 0, "")
+	pdf.SetFont("helvetica", "", 11)
+	pdf.SetXY(left+inset, top+56)
+	pdf.CellFormat(width, 14, seatLabel(sg), "", 0, "CM", false, 0, "")
+}
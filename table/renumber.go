@@ -0,0 +1,129 @@
+package table
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/scholacantorum/gala-backend/db"
+	"github.com/scholacantorum/gala-backend/journal"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// defaultRowTolerance is the default vertical distance, in pixels on the
+// tables page, within which tables are considered to be in the same row.
+const defaultRowTolerance = 40
+
+// serveRenumber handles POST /tables/renumber.  It renumbers the numbered
+// tables in order of their positions on the tables page, top to bottom and
+// then left to right.  The body is a JSON object:
+//
+//	order:         "rows" (every row left to right; the default) or
+//	               "serpentine" (alternate rows right to left)
+//	exclude:       IDs of tables that keep their current numbers (e.g. head
+//	               tables); their numbers are skipped over
+//	rowTolerance:  vertical distance within which tables are considered to
+//	               be in the same row (default 40)
+//
+// Tables that have numbers but no positions are numbered last, in their
+// current order.  All of the changes are made in a single journal entry.
+func serveRenumber(w *request.ResponseWriter, r *request.Request) {
+	var (
+		body struct {
+			Order        string  `json:"order"`
+			Exclude      []db.ID `json:"exclude"`
+			RowTolerance int     `json:"rowTolerance"`
+		}
+		exclude  = make(map[db.ID]bool)
+		reserved = make(map[int]bool)
+		placed   []*model.Table
+		unplaced []*model.Table
+		numbers  = make(map[db.ID]int)
+		je       model.JournalEntry
+		err      error
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("serveRenumber JSON decode %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch body.Order {
+	case "":
+		body.Order = "rows"
+	case "rows", "serpentine":
+		break
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.RowTolerance <= 0 {
+		body.RowTolerance = defaultRowTolerance
+	}
+	for _, id := range body.Exclude {
+		exclude[id] = true
+	}
+	model.FetchTables(r.Tx, func(t *model.Table) {
+		var copy = *t
+		switch {
+		case exclude[t.ID]:
+			reserved[t.Number] = true
+		case t.X == 0 && t.Y == 0:
+			unplaced = append(unplaced, &copy)
+		default:
+			placed = append(placed, &copy)
+		}
+	}, "num!=0 ORDER BY num")
+	placed = orderTablesByPosition(placed, body.Order == "serpentine", body.RowTolerance)
+	num := 1
+	for _, t := range append(placed, unplaced...) {
+		for reserved[num] {
+			num++
+		}
+		if t.Number != num {
+			numbers[t.ID] = num
+		}
+		num++
+	}
+	if len(numbers) != 0 {
+		model.RenumberTables(r.Tx, &je, numbers)
+		journal.Log(r, &je)
+	}
+	w.CommitNoContent(r)
+}
+
+// orderTablesByPosition sorts tables into rows, top to bottom, and each row
+// left to right (or, for serpentine order, alternately left to right and right
+// to left).  A table belongs to the same row as the one above it if its y
+// coordinate is within tolerance of the top of the row.
+func orderTablesByPosition(tables []*model.Table, serpentine bool, tolerance int) (ordered []*model.Table) {
+	var rows [][]*model.Table
+
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].Y < tables[j].Y })
+	for _, t := range tables {
+		if len(rows) == 0 || t.Y-rows[len(rows)-1][0].Y > tolerance {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], t)
+	}
+	for i, row := range rows {
+		reverse := serpentine && i%2 == 1
+		sort.SliceStable(row, func(a, b int) bool {
+			if reverse {
+				return row[a].X > row[b].X
+			}
+			return row[a].X < row[b].X
+		})
+		ordered = append(ordered, row...)
+	}
+	return ordered
+}
@@ -0,0 +1,60 @@
+package table
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/scholacantorum/gala-backend/model"
+)
+
+// seatedGuest is a guest, along with the table at which they are seated.
+type seatedGuest struct {
+	Table *model.Table
+	Guest *model.Guest
+}
+
+// fetchSeatedGuests returns all guests who are attending (i.e., not
+// cancelled), ordered by table number and then by seat number.  Guests without
+// seats come after the others at their table, in party order, and guests at
+// tables without numbers come last.
+func fetchSeatedGuests(tx *sqlx.Tx) (seated []seatedGuest) {
+	var tables []*model.Table
+
+	model.FetchTables(tx, func(t *model.Table) {
+		var copy = *t
+		tables = append(tables, &copy)
+	}, "1 ORDER BY num=0, num")
+	for _, t := range tables {
+		var atTable []seatedGuest
+
+		model.FetchPartiesAtTable(tx, t.ID, func(p *model.Party) {
+			model.FetchGuestsInParty(tx, p.ID, func(g *model.Guest) {
+				if !g.Cancelled {
+					var copy = *g
+					atTable = append(atTable, seatedGuest{t, &copy})
+				}
+			})
+		})
+		sort.SliceStable(atTable, func(i, j int) bool {
+			si, sj := atTable[i].Guest.Seat, atTable[j].Guest.Seat
+			return si != 0 && (sj == 0 || si < sj)
+		})
+		seated = append(seated, atTable...)
+	}
+	return seated
+}
+
+// seatLabel returns a description of the guest's table and seat, such as
+// "Table 3, Seat 5", or an empty string if they aren't at a numbered table.
+func seatLabel(sg seatedGuest) string {
+	switch {
+	case sg.Table.Number == 0:
+		return ""
+	case sg.Guest.Seat == 0:
+		return fmt.Sprintf("Table %d", sg.Table.Number)
+	default:
+		return fmt.Sprintf("Table %d, Seat %d", sg.Table.Number, sg.Guest.Seat)
+	}
+}
@@ -0,0 +1,106 @@
+package table
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/scholacantorum/gala-backend/config"
+	"github.com/scholacantorum/gala-backend/model"
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// serveServerSheet handles GET /tables/server-sheet.pdf.  It returns the
+// instructions for the servers: for each numbered table, what to serve at each
+// seat, and the dietary restrictions of the guest in that seat.
+func serveServerSheet(w *request.ResponseWriter, r *request.Request) {
+	const (
+		left       = 54.0
+		width      = 504.0
+		seatWidth  = 36.0
+		nameWidth  = 144.0
+		mealWidth  = 108.0
+		lineHeight = 14.0
+	)
+	var (
+		pdf     *gofpdf.Fpdf
+		tr      func(string) string
+		buf     bytes.Buffer
+		tables  [][]seatedGuest
+		entrees = make(map[string]string)
+	)
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	model.FetchEntrees(r.Tx, func(e *model.Entree) { entrees[e.Code] = e.Name }, "")
+	for _, sg := range fetchSeatedGuests(r.Tx) {
+		if sg.Table.Number == 0 {
+			continue
+		}
+		if len(tables) == 0 || tables[len(tables)-1][0].Table.ID != sg.Table.ID {
+			tables = append(tables, nil)
+		}
+		tables[len(tables)-1] = append(tables[len(tables)-1], sg)
+	}
+	pdf = gofpdf.New("P", "pt", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(left, 54, left)
+	pdf.SetAutoPageBreak(true, 54)
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("helvetica", "B", 14)
+		pdf.CellFormat(width, 20, tr(fmt.Sprintf("%s — Server Instructions", config.Get("galaTitle"))), "", 1, "L", false, 0, "")
+		pdf.Ln(6)
+	})
+	pdf.AddPage()
+	for _, guests := range tables {
+		// Keep each table together on a page.
+		_, pageHeight := pdf.GetPageSize()
+		if pdf.GetY()+lineHeight*float64(len(guests)+2) > pageHeight-54 {
+			pdf.AddPage()
+		}
+		table := guests[0].Table
+		heading := "Table " + strconv.Itoa(table.Number)
+		if table.Name != "" {
+			heading += " (" + table.Name + ")"
+		}
+		pdf.SetFont("helvetica", "B", 11)
+		pdf.CellFormat(width, lineHeight+2, tr(heading), "B", 1, "L", false, 0, "")
+		pdf.SetFont("helvetica", "", 10)
+		for _, sg := range guests {
+			seat, entree := "—", "not selected"
+			if sg.Guest.Seat != 0 {
+				seat = strconv.Itoa(sg.Guest.Seat)
+			}
+			if sg.Guest.Entree != "" {
+				if entree = entrees[sg.Guest.Entree]; entree == "" {
+					entree = sg.Guest.Entree
+				}
+			}
+			pdf.CellFormat(seatWidth, lineHeight, tr(seat), "", 0, "R", false, 0, "")
+			pdf.CellFormat(12, lineHeight, "", "", 0, "L", false, 0, "")
+			pdf.CellFormat(nameWidth, lineHeight, tr(sg.Guest.Name), "", 0, "L", false, 0, "")
+			pdf.CellFormat(mealWidth, lineHeight, tr(entree), "", 0, "L", false, 0, "")
+			pdf.CellFormat(width-seatWidth-12-nameWidth-mealWidth, lineHeight,
+				tr(strings.Join(sg.Guest.DietaryNeeds(), "; ")), "", 1, "L", false, 0, "")
+		}
+		pdf.Ln(8)
+	}
+	if err := pdf.Output(&buf); err != nil {
+		log.Printf("PDF ERROR: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="server-sheet.pdf"`)
+	w.Write(buf.Bytes())
+}
@@ -37,6 +37,8 @@ func ServeTable(w *request.ResponseWriter, r *request.Request) {
 	switch head {
 	case "":
 		serveTable(w, r, table)
+	case "seats":
+		serveTableSeats(w, r, table)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -65,7 +67,7 @@ func saveTable(w *request.ResponseWriter, r *request.Request, table *model.Table
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if body.ID != table.ID || body.Number < 0 {
+	if body.ID != table.ID || body.Number < 0 || body.Capacity < 0 {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -81,6 +83,25 @@ func saveTable(w *request.ResponseWriter, r *request.Request, table *model.Table
 	w.CommitNoContent(r)
 }
 
+// serveTableSeats handles POST /table/${pid}/seats.  It discards the seat
+// assignments at the table and assigns them afresh, so that every party sits
+// together.
+func serveTableSeats(w *request.ResponseWriter, r *request.Request, table *model.Table) {
+	var je model.JournalEntry
+
+	if r.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	model.ReassignSeats(r.Tx, &je, table)
+	journal.Log(r, &je)
+	w.CommitNoContent(r)
+}
+
 // serveRepositionTables handles POST /table/reposition.  It takes a JSON array
 // of tables — really {id,x,y} tuples — and updates the (x,y) coordinates of
 // each of the identified tables.
@@ -0,0 +1,39 @@
+package table
+
+import (
+	"net/http"
+
+	"github.com/scholacantorum/gala-backend/request"
+)
+
+// ServeTables handles requests starting with /tables.
+func ServeTables(w *request.ResponseWriter, r *request.Request) {
+	var (
+		head string
+	)
+	head, r.URL.Path = request.ShiftPath(r.URL.Path)
+	switch head {
+	case "accessibility.pdf":
+		serveAccessibility(w, r)
+	case "auto-seat":
+		serveAutoSeat(w, r)
+	case "caterer.csv":
+		serveCatererCSV(w, r)
+	case "caterer.pdf":
+		serveCatererPDF(w, r)
+	case "chart.pdf":
+		serveChartPDF(w, r)
+	case "chart.svg":
+		serveChartSVG(w, r)
+	case "entree-cards.pdf":
+		serveEntreeCards(w, r)
+	case "place-cards.pdf":
+		servePlaceCards(w, r)
+	case "renumber":
+		serveRenumber(w, r)
+	case "server-sheet.pdf":
+		serveServerSheet(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}